@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// valueCrossReference relates the keys each value source defines to the keys
+// the templates reference
+type valueCrossReference struct {
+	// Unused lists, per source in load order, the sorted keys no template references
+	Unused []sourceKeys
+	// Undefined lists the keys templates reference that no source defines
+	Undefined []undefinedKey
+}
+
+// sourceKeys is a set of keys defined by one value source
+type sourceKeys struct {
+	Source string
+	Keys   []string
+}
+
+// undefinedKey is a key a template references that no value source defines
+type undefinedKey struct {
+	Template string
+	Key      string
+	// Locations are where the template references the key
+	Locations []string
+}
+
+// crossReferenceValues finds the keys defined in sources that no template uses
+// and the keys templates use that no source defines. A key a value in an
+// interpolated source references with ${Key} counts as used.
+func crossReferenceValues(sources []valueSource, deps []templateDeps) *valueCrossReference {
+	used := make(map[string]bool)
+	for _, d := range deps {
+		for _, key := range d.Keys {
+			used[key] = true
+		}
+	}
+	for _, source := range sources {
+		if source.Secret || source.Literal {
+			continue
+		}
+		for _, key := range utils.ReferencedKeys(source.Values) {
+			used[key] = true
+		}
+	}
+
+	xref := &valueCrossReference{}
+	defined := make(map[string]bool)
+	for _, source := range sources {
+		var unused []string
+		for key := range source.Values {
+			defined[key] = true
+			if !used[key] {
+				unused = append(unused, key)
+			}
+		}
+		if len(unused) > 0 {
+			sort.Strings(unused)
+			xref.Unused = append(xref.Unused, sourceKeys{Source: source.Name, Keys: unused})
+		}
+	}
+
+	for _, d := range deps {
+		for _, key := range d.Keys {
+			if defined[key] {
+				continue
+			}
+			missing := undefinedKey{Template: d.Path, Key: key}
+			for _, ref := range d.Refs {
+				if ref.Key == key {
+					missing.Locations = append(missing.Locations, ref.Location)
+				}
+			}
+			if len(missing.Locations) == 0 {
+				missing.Locations = []string{d.Path}
+			}
+			xref.Undefined = append(xref.Undefined, missing)
+		}
+	}
+	sort.SliceStable(xref.Undefined, func(i, j int) bool {
+		return xref.Undefined[i].Template < xref.Undefined[j].Template
+	})
+	return xref
+}
+
+// printUnused writes the unused keys of each value source
+func (x *valueCrossReference) printUnused(w io.Writer) {
+	for _, unused := range x.Unused {
+		fmt.Fprintf(w, "ℹ %d unused key(s) in %s: %s\n", len(unused.Keys), unused.Source, strings.Join(unused.Keys, ", "))
+	}
+}
+
+// printUndefined writes each template's undefined keys with where they are referenced
+func (x *valueCrossReference) printUndefined(w io.Writer) {
+	template := ""
+	for _, missing := range x.Undefined {
+		if missing.Template != template {
+			template = missing.Template
+			fmt.Fprintf(w, "❌ Undefined key(s) in %s:\n", template)
+		}
+		fmt.Fprintf(w, "    %s (%s)\n", missing.Key, strings.Join(missing.Locations, ", "))
+	}
+}
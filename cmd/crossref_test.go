@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrossReferenceValues(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{
+		"_partials/redis.tmpl": "{{ define \"redis\" }}\nhost: {{ .RedisHost }}\n{{ end }}",
+		"__.OrgName__/svc/config.yaml.tmpl": "name: {{ .OrgName }}\n" +
+			"{{ template \"redis\" . }}\n" +
+			"{{ range .Projects }}{{ $.SlackLinerList }}{{ end }}\n" +
+			"list: {{ .SlackLinerList }}\n",
+		"__.OrgName__/svc/.env.tmpl":        "---\nwhen: .Enabled\n---\nPASSWORD={{ .RedisPassword }}\n",
+		"__each.Projects__/deploy.txt.tmpl": "{{ .Project.name }}",
+		"__.Env__/notes.txt.tmpl":           "static",
+	})
+	deps, err := analyzeTemplateDeps([]string{sourceDir}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sources := []valueSource{
+		{Name: "values.json", Values: map[string]interface{}{"OrgName": "org", "RedisPassword": "pw", "Stale": "${Host}:6379", "Host": "redis", "Enabled": true}},
+		{Name: "projects.json", Values: map[string]interface{}{"Projects": []map[string]interface{}{}}},
+		{Name: "ports.json", Values: map[string]interface{}{"old-port": "1"}},
+	}
+	xref := crossReferenceValues(sources, deps)
+
+	expectedUnused := []sourceKeys{
+		{Source: "values.json", Keys: []string{"Stale"}},
+		{Source: "ports.json", Keys: []string{"old-port"}},
+	}
+	if !reflect.DeepEqual(xref.Unused, expectedUnused) {
+		t.Errorf("Unused = %+v, want %+v", xref.Unused, expectedUnused)
+	}
+
+	config := filepath.Join(sourceDir, "__.OrgName__", "svc", "config.yaml.tmpl")
+	notes := filepath.Join(sourceDir, "__.Env__", "notes.txt.tmpl")
+	expectedUndefined := []undefinedKey{
+		{Template: notes, Key: "Env", Locations: []string{notes}},
+		{Template: config, Key: "RedisHost", Locations: []string{filepath.Join(sourceDir, "_partials", "redis.tmpl") + ":2"}},
+		{Template: config, Key: "SlackLinerList", Locations: []string{config + ":3", config + ":4"}},
+	}
+	if !reflect.DeepEqual(xref.Undefined, expectedUndefined) {
+		t.Errorf("Undefined = %+v, want %+v", xref.Undefined, expectedUndefined)
+	}
+
+	var out bytes.Buffer
+	xref.printUndefined(&out)
+	expectedOut := "❌ Undefined key(s) in " + notes + ":\n" +
+		"    Env (" + notes + ")\n" +
+		"❌ Undefined key(s) in " + config + ":\n" +
+		"    RedisHost (" + filepath.Join(sourceDir, "_partials", "redis.tmpl") + ":2)\n" +
+		"    SlackLinerList (" + config + ":3, " + config + ":4)\n"
+	if out.String() != expectedOut {
+		t.Errorf("printUndefined() =\n%s\nwant\n%s", out.String(), expectedOut)
+	}
+
+	out.Reset()
+	xref.printUnused(&out)
+	if !strings.Contains(out.String(), "1 unused key(s) in ports.json: old-port") {
+		t.Errorf("printUnused() = %q", out.String())
+	}
+}
+
+func TestCrossReferenceValues_FrontMatterLocation(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{
+		"a.txt.tmpl": "---\nwhen: .Enabled\n---\nstatic\n",
+	})
+	deps, err := analyzeTemplateDeps([]string{sourceDir}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	xref := crossReferenceValues(nil, deps)
+	path := filepath.Join(sourceDir, "a.txt.tmpl")
+	expected := []undefinedKey{{Template: path, Key: "Enabled", Locations: []string{path + ":1"}}}
+	if !reflect.DeepEqual(xref.Undefined, expected) {
+		t.Errorf("Undefined = %+v, want %+v", xref.Undefined, expected)
+	}
+}
+
+func TestCrossReferenceValues_ExampleFiles(t *testing.T) {
+	// make validate-json runs against the example files, so they must define every key the stock templates use
+	deps, err := analyzeTemplateDeps([]string{filepath.Join("..", "source")}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sources []valueSource
+	for _, name := range []string{"values", "ports", "projects"} {
+		data, err := os.ReadFile(filepath.Join("..", name+".json.example"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var values interface{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			t.Fatal(err)
+		}
+		if name == "projects" {
+			values = map[string]interface{}{"Projects": values}
+		}
+		sources = append(sources, valueSource{Name: name + ".json.example", Values: values.(map[string]interface{})})
+	}
+
+	xref := crossReferenceValues(sources, deps)
+	if len(xref.Undefined) > 0 {
+		var out bytes.Buffer
+		xref.printUndefined(&out)
+		t.Errorf("expected the example files to define every referenced key:\n%s", out.String())
+	}
+}
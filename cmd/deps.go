@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// pathVarPattern matches the __.Key__ placeholders expanded in source paths
+var pathVarPattern = regexp.MustCompile(`__\.(.+?)__`)
+
+// NewDepsCmd creates the deps command
+func NewDepsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps",
+		Short: "Report which value keys each template references",
+		Long: `Statically analyse every template in the source directories and report the
+top-level value keys each one references, along with the inverse map of keys to
+the templates that use them. No values are loaded and nothing is rendered.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceDirs, _ := cmd.Flags().GetStringSlice("source-dir")
+			followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+			output, _ := cmd.Flags().GetString("output")
+
+			deps, err := analyzeTemplateDeps(sourceDirs, followSymlinks)
+			if err != nil {
+				return fmt.Errorf("error analysing templates: %w", err)
+			}
+
+			switch output {
+			case "text":
+				return writeDepsText(os.Stdout, deps)
+			case "json":
+				return writeDepsJSON(os.Stdout, deps)
+			default:
+				return fmt.Errorf("invalid --output %q: expected text or json", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceP("source-dir", "s", []string{"source"}, "Source directories to analyse, in layer order")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symlinks in the source directories")
+	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	return cmd
+}
+
+// templateDeps lists the value keys a template depends on
+type templateDeps struct {
+	// Layer is the source directory the template was found in
+	Layer string
+	// Path is the template file path and RelPath is relative to Layer
+	Path    string
+	RelPath string
+	// Keys are the sorted top-level value keys the template references
+	Keys []string
+	// Refs locate every reference to Keys, in source order
+	Refs []keyLocation
+}
+
+// keyLocation is where a template references a value key
+type keyLocation struct {
+	Key string
+	// Location is file:line for references in a template or partial body (line 1
+	// for front matter), or the template path for __.Key__ path placeholders
+	Location string
+}
+
+// analyzeTemplateDeps parses every template in the source layers and returns the
+// value keys each references in its body, its front matter, the partials it calls
+// and the __.Key__ placeholders in its path. A __each.Projects__ template depends on
+// Projects, which its .Project references are bound from, and a template in a service
+// directory depends on BaseDir for its .ServiceDir references. Partials themselves
+// are not listed; their keys are attributed to the templates that call them.
+func analyzeTemplateDeps(sourceDirs []string, followSymlinks bool) ([]templateDeps, error) {
+	partials, err := loadPartials(sourceDirs, followSymlinks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load partials: %w", err)
+	}
+
+	var deps []templateDeps
+	for _, sourceDir := range sourceDirs {
+		walkFn := func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".tmpl") {
+				return nil
+			}
+
+			relPath, err := filepath.Rel(sourceDir, path)
+			if err != nil {
+				return fmt.Errorf("failed to get relative path: %w", err)
+			}
+			if isPartialPath(relPath) {
+				return nil
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read template file %s: %w", path, err)
+			}
+			parsed, err := parseTemplate(path, content, partials)
+			if err != nil {
+				return fmt.Errorf("failed to process template %s: %w", path, err)
+			}
+
+			keys := mergeValueKeys(parsed.valueKeys(), pathValueKeys(relPath))
+			if fanOutPattern.MatchString(relPath) {
+				keys = fanOutValueKeys(keys)
+			}
+			if serviceDirOf(relPath) != "" {
+				keys = serviceValueKeys(keys)
+			}
+
+			deps = append(deps, templateDeps{Layer: sourceDir, Path: path, RelPath: relPath, Keys: keys, Refs: parsed.keyLocations(relPath)})
+			return nil
+		}
+
+		var err error
+		if followSymlinks {
+			err = walkDirFollowSymlinks(sourceDir, walkFn)
+		} else {
+			err = filepath.WalkDir(sourceDir, walkFn)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return deps, nil
+}
+
+// keyLocations locates the value key references of a parsed template whose path
+// relative to its layer is relPath
+func (p *parsedTemplate) keyLocations(relPath string) []keyLocation {
+	var locations []keyLocation
+	for _, key := range pathValueKeys(relPath) {
+		locations = append(locations, keyLocation{Key: key, Location: p.Path})
+	}
+	for _, key := range p.FrontMatter.valueKeys() {
+		locations = append(locations, keyLocation{Key: key, Location: p.Path + ":1"})
+	}
+	for _, ref := range templateValueKeyRefs(p.Tmpl) {
+		// Partials are parsed under their file path, the template under its base name
+		name, line := ref.location()
+		if name == p.Tmpl.Name() {
+			name = p.Path
+		}
+		locations = append(locations, keyLocation{Key: ref.Key, Location: fmt.Sprintf("%s:%d", name, line)})
+	}
+	return locations
+}
+
+// pathValueKeys returns the keys of the __.Key__ placeholders in a source path
+func pathValueKeys(relPath string) []string {
+	var keys []string
+	for _, m := range pathVarPattern.FindAllStringSubmatch(filepath.ToSlash(relPath), -1) {
+		keys = append(keys, m[1])
+	}
+	return mergeValueKeys(keys, nil)
+}
+
+// keyTemplates inverts template dependencies into a map of each key to the
+// sorted paths of the templates referencing it
+func keyTemplates(deps []templateDeps) map[string][]string {
+	byKey := make(map[string][]string)
+	for _, d := range deps {
+		for _, key := range d.Keys {
+			byKey[key] = append(byKey[key], d.Path)
+		}
+	}
+	for key := range byKey {
+		sort.Strings(byKey[key])
+	}
+	return byKey
+}
+
+// writeDepsText writes a template-to-keys table followed by a key-to-templates table
+func writeDepsText(w io.Writer, deps []templateDeps) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TEMPLATE\tKEYS")
+	for _, d := range deps {
+		fmt.Fprintf(tw, "%s\t%s\n", d.Path, strings.Join(d.Keys, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	byKey := keyTemplates(deps)
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(tw, "KEY\tTEMPLATES")
+	for _, key := range keys {
+		for i, path := range byKey[key] {
+			if i == 0 {
+				fmt.Fprintf(tw, "%s\t%s\n", key, path)
+			} else {
+				fmt.Fprintf(tw, "\t%s\n", path)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// depsReport is the JSON form of the deps command's output
+type depsReport struct {
+	Templates map[string][]string `json:"templates"`
+	Keys      map[string][]string `json:"keys"`
+}
+
+// writeDepsJSON writes the template-to-keys and key-to-templates maps as JSON
+func writeDepsJSON(w io.Writer, deps []templateDeps) error {
+	report := depsReport{
+		Templates: make(map[string][]string, len(deps)),
+		Keys:      keyTemplates(deps),
+	}
+	for _, d := range deps {
+		report.Templates[d.Path] = d.Keys
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeTemplateDeps(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{
+		"_partials/redis.tmpl":                  `{{ define "redis" }}{{ .RedisHost }}{{ end }}`,
+		"__.OrgName__/svc/.env.tmpl":            `PORT={{ index . "github-webhook-port" }}`,
+		"__.OrgName__/svc/config.yaml.tmpl":     `{{ template "redis" . }}{{ range .Projects }}{{ .name }}{{ $.BaseDir }}{{ end }}`,
+		"__.OrgName__/svc/gated.txt.tmpl":       "---\nwhen: .Enabled\n---\nstatic",
+		"__each.Projects__/deploy.txt.tmpl":     `{{ .Project.name }} {{ .Region }}`,
+		"__.OrgName__/svc/notes.md":             `{{ .NotATemplate }}`,
+		"__.OrgName__/svc/other.partial.tmpl":   `{{ define "other" }}{{ .Unused }}{{ end }}`,
+		"__.OrgName__/__.Env__/nested.txt.tmpl": `plain`,
+	})
+
+	deps, err := analyzeTemplateDeps([]string{sourceDir}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string][]string)
+	for _, d := range deps {
+		if d.Layer != sourceDir || d.Path != filepath.Join(sourceDir, d.RelPath) {
+			t.Errorf("unexpected layer or path for %s: %+v", d.RelPath, d)
+		}
+		got[filepath.ToSlash(d.RelPath)] = d.Keys
+	}
+	expected := map[string][]string{
+		"__.OrgName__/svc/.env.tmpl":            {"OrgName", "github-webhook-port"},
+		"__.OrgName__/svc/config.yaml.tmpl":     {"BaseDir", "OrgName", "Projects", "RedisHost"},
+		"__.OrgName__/svc/gated.txt.tmpl":       {"Enabled", "OrgName"},
+		"__each.Projects__/deploy.txt.tmpl":     {"Projects", "Region"},
+		"__.OrgName__/__.Env__/nested.txt.tmpl": {"Env", "OrgName"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("analyzeTemplateDeps() = %v, want %v", got, expected)
+	}
+}
+
+func TestKeyTemplates(t *testing.T) {
+	deps := []templateDeps{
+		{Path: "source/b.tmpl", Keys: []string{"OrgName", "RedisHost"}},
+		{Path: "source/a.tmpl", Keys: []string{"OrgName"}},
+	}
+	expected := map[string][]string{
+		"OrgName":   {"source/a.tmpl", "source/b.tmpl"},
+		"RedisHost": {"source/b.tmpl"},
+	}
+	if got := keyTemplates(deps); !reflect.DeepEqual(got, expected) {
+		t.Errorf("keyTemplates() = %v, want %v", got, expected)
+	}
+}
+
+func TestWriteDeps(t *testing.T) {
+	deps := []templateDeps{
+		{Path: "source/a.tmpl", Keys: []string{"OrgName", "RedisHost"}},
+		{Path: "source/b.tmpl", Keys: []string{}},
+	}
+
+	var text bytes.Buffer
+	if err := writeDepsText(&text, deps); err != nil {
+		t.Fatalf("writeDepsText() error: %v", err)
+	}
+	for _, line := range []string{
+		"TEMPLATE       KEYS",
+		"source/a.tmpl  OrgName, RedisHost",
+		"KEY        TEMPLATES",
+		"OrgName    source/a.tmpl",
+		"RedisHost  source/a.tmpl",
+	} {
+		if !strings.Contains(text.String(), line+"\n") {
+			t.Errorf("text output missing line %q:\n%s", line, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeDepsJSON(&out, deps); err != nil {
+		t.Fatalf("writeDepsJSON() error: %v", err)
+	}
+	var report depsReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if !reflect.DeepEqual(report.Templates["source/b.tmpl"], []string{}) || !reflect.DeepEqual(report.Keys["OrgName"], []string{"source/a.tmpl"}) {
+		t.Errorf("unexpected JSON report: %+v", report)
+	}
+}
@@ -110,6 +110,19 @@ func getDiffChangedServices() ([]string, error) {
 		return []string{}, nil
 	}
 
+	// When both builds have a manifest, compare their content hashes instead of the trees
+	prevManifest, err := loadManifest("prev-build")
+	if err != nil {
+		return nil, err
+	}
+	curManifest, err := loadManifest("build")
+	if err != nil {
+		return nil, err
+	}
+	if prevManifest != nil && curManifest != nil {
+		return changedServicesFromManifests(prevManifest, curManifest), nil
+	}
+
 	// Run diff -qr prev-build build
 	diffCmd := exec.Command("diff", "-qr", "prev-build", "build")
 	output, err := diffCmd.CombinedOutput()
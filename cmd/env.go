@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// envVariable is the environment variable selecting a profile when --env is not given
+const envVariable = "VIBEOPS_ENV"
+
+// envNamePattern restricts profile names to ones that are safe in file names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// addEnvFlag adds the --env flag selecting an environment profile
+func addEnvFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("env", "e", "", "Environment profile whose values.<env>, ports.<env> and bootstrap.<env>.json are layered over the base files (default $"+envVariable+")")
+}
+
+// resolveEnv returns the profile selected by --env or VIBEOPS_ENV, or "" for none
+func resolveEnv(cmd *cobra.Command) (string, error) {
+	env, _ := cmd.Flags().GetString("env")
+	if !cmd.Flags().Changed("env") {
+		env = os.Getenv(envVariable)
+	}
+	if env == "" {
+		return "", nil
+	}
+	if !envNamePattern.MatchString(env) {
+		return "", fmt.Errorf("invalid environment %q: use only letters, digits, '-' and '_'", env)
+	}
+	if isReservedEnv(env) {
+		return "", fmt.Errorf("invalid environment %q: values.%[1]s.json is the values schema", env)
+	}
+	return env, nil
+}
+
+// envBuildDir returns the build directory to use: --build-dir when given, else
+// the default suffixed with the profile, e.g. build-prod, so profiles built on
+// one machine never overwrite each other
+func envBuildDir(cmd *cobra.Command, env string) string {
+	buildDir, _ := cmd.Flags().GetString("build-dir")
+	if env != "" && !cmd.Flags().Changed("build-dir") {
+		return buildDir + "-" + env
+	}
+	return buildDir
+}
+
+// envBootstrapFile returns the bootstrap file of a profile
+func envBootstrapFile(env string) string {
+	return "bootstrap." + env + ".json"
+}
+
+// envValueFileCandidates returns every file a profile may be defined in
+func envValueFileCandidates(env string) []string {
+	candidates := append(utils.ValuesFileCandidates("values."+env), utils.ValuesFileCandidates("ports."+env)...)
+	return append(candidates, envBootstrapFile(env))
+}
+
+// loadEnvSources loads the values and ports files of a profile and its bootstrap
+// config. Each is optional, but a profile without any of them is an error since
+// the name is most likely a typo.
+func loadEnvSources(env string) ([]valueSource, *utils.BootstrapConfig, error) {
+	var sources []valueSource
+	for _, name := range []string{"values", "ports"} {
+		file, err := utils.FindValuesFile(name + "." + env)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !fileExists(file) {
+			continue
+		}
+		values, err := utils.LoadValuesFromFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading %s: %w", file, err)
+		}
+		sources = append(sources, valueSource{Name: file, Values: values})
+	}
+
+	var bootstrap *utils.BootstrapConfig
+	if bootstrapFile := envBootstrapFile(env); fileExists(bootstrapFile) {
+		config, err := utils.LoadBootstrapConfig(bootstrapFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading %s: %w", bootstrapFile, err)
+		}
+		bootstrap = config
+	}
+
+	if len(sources) == 0 && bootstrap == nil {
+		return nil, nil, fmt.Errorf("environment %q has no values.%[1]s, ports.%[1]s or %s file", env, envBootstrapFile(env))
+	}
+	return sources, bootstrap, nil
+}
+
+// discoverEnvs lists the profiles with a values, ports or bootstrap file in the
+// working directory, sorted
+func discoverEnvs() ([]string, error) {
+	found := make(map[string]bool)
+	for _, name := range []string{"values", "ports", "bootstrap"} {
+		matches, err := filepath.Glob(name + ".*.*")
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			env, ext, ok := strings.Cut(strings.TrimPrefix(match, name+"."), ".")
+			if !ok || !envNamePattern.MatchString(env) || isReservedEnv(env) {
+				continue
+			}
+			if name == "bootstrap" && ext == "json" || name != "bootstrap" && isValuesFileExtension("."+ext) {
+				found[env] = true
+			}
+		}
+	}
+
+	envs := make([]string, 0, len(found))
+	for env := range found {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs, nil
+}
+
+// isReservedEnv reports whether env cannot be a profile because values.<env>.json
+// is another file, namely values.schema.json
+func isReservedEnv(env string) bool {
+	return "values."+env+".json" == valuesSchemaFile
+}
+
+// isValuesFileExtension reports whether ext is a supported values file extension
+func isValuesFileExtension(ext string) bool {
+	for _, supported := range utils.ValuesFileExtensions {
+		if ext == supported {
+			return true
+		}
+	}
+	return false
+}
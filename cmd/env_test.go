@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestResolveEnv(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringP("build-dir", "b", "build", "")
+		addEnvFlag(cmd)
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+
+	t.Setenv(envVariable, "")
+	if env, err := resolveEnv(newCmd()); err != nil || env != "" {
+		t.Errorf("resolveEnv() = %q, %v, want no profile", env, err)
+	}
+
+	t.Setenv(envVariable, "staging")
+	if env, err := resolveEnv(newCmd()); err != nil || env != "staging" {
+		t.Errorf("resolveEnv() = %q, %v, want staging from %s", env, err, envVariable)
+	}
+	cmd := newCmd("--env", "prod")
+	if env, err := resolveEnv(cmd); err != nil || env != "prod" {
+		t.Errorf("resolveEnv() = %q, %v, want --env to win over %s", env, err, envVariable)
+	}
+	if _, err := resolveEnv(newCmd("--env", "../prod")); err == nil {
+		t.Error("expected an error for an env name that is not a plain name")
+	}
+
+	if buildDir := envBuildDir(cmd, "prod"); buildDir != "build-prod" {
+		t.Errorf("envBuildDir() = %q, want build-prod", buildDir)
+	}
+	if buildDir := envBuildDir(newCmd("--env", "prod", "-b", "out"), "prod"); buildDir != "out" {
+		t.Errorf("envBuildDir() = %q, want an explicit --build-dir to be kept", buildDir)
+	}
+	if buildDir := envBuildDir(newCmd(), ""); buildDir != "build" {
+		t.Errorf("envBuildDir() = %q, want build without a profile", buildDir)
+	}
+}
+
+func TestDiscoverEnvs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":            "{}",
+		"values.json.example":    "{}",
+		"values.dev.yaml":        "{}",
+		"ports.prod.json":        "{}",
+		"bootstrap.json.example": "{}",
+		"bootstrap.staging.json": "{}",
+		"ports.old.bak":          "{}",
+		"values.schema.json":     "{}",
+	})
+
+	envs, err := discoverEnvs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"dev", "prod", "staging"}; !reflect.DeepEqual(envs, expected) {
+		t.Errorf("discoverEnvs() = %v, want %v", envs, expected)
+	}
+}
+
+func TestLoadValueSources_Env(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":         `{"BaseDir": "/srv", "Redis": {"Host": "localhost", "Port": "6379"}}`,
+		"projects.json":       `[]`,
+		"ports.json":          `{"api": "8080"}`,
+		"values.prod.yaml":    "BaseDir: /opt\nRedis:\n  Host: redis.internal\n",
+		"ports.prod.json":     `{"api": "9090"}`,
+		"bootstrap.prod.json": `{"ValuesMerge": {"Mode": "deep"}}`,
+	})
+
+	set, err := loadValueSources("prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, source := range set.Sources {
+		names = append(names, source.Name)
+	}
+	if expected := []string{"values.json", "projects.json", "ports.json", "values.prod.yaml", "ports.prod.json"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("sources = %v, want %v", names, expected)
+	}
+
+	// bootstrap.prod.json switches to a deep merge, keeping the base Redis port
+	values, err := mergeValueSources(set.Sources, set.Merge)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRedis := map[string]interface{}{"Host": "redis.internal", "Port": "6379"}
+	if values["BaseDir"] != "/opt" || values["api"] != "9090" || !reflect.DeepEqual(values["Redis"], expectedRedis) {
+		t.Errorf("unexpected merged values: %v", values)
+	}
+
+	if _, err := loadValueSources("prdo"); err == nil || !strings.Contains(err.Error(), `environment "prdo" has no`) {
+		t.Errorf("expected an error for a profile without files, got %v", err)
+	}
+}
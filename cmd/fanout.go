@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fanOutPattern matches a __each.Projects__ path segment, optionally filtered on a
+// project field: __each.Projects.allowVibeDeploy__ keeps projects where the field is
+// truthy and __each.Projects.language=go__ keeps projects where it equals the value
+var fanOutPattern = regexp.MustCompile(`__each\.Projects(?:\.(\w+?)(?:=(.*?))?)?__`)
+
+// fanOutProjectKey is the value key each fanned-out template sees its project under
+const fanOutProjectKey = "Project"
+
+// fanOutInstance is one expansion of a source path containing a fan-out segment
+type fanOutInstance struct {
+	RelPath string
+	// Project is the projects.json entry the path was expanded for, or nil when
+	// the path has no fan-out segment
+	Project map[string]interface{}
+}
+
+// expandFanOut expands a __each.Projects__ segment in relPath once per matching
+// project, replacing the segment with the project's name. Paths without a fan-out
+// segment are returned unchanged as a single instance. The same segment may appear
+// more than once (e.g. in a directory and a file name); different segments may not.
+func expandFanOut(relPath string, values map[string]interface{}) ([]fanOutInstance, error) {
+	matches := fanOutPattern.FindAllStringSubmatch(relPath, -1)
+	if len(matches) == 0 {
+		return []fanOutInstance{{RelPath: relPath}}, nil
+	}
+	for _, m := range matches[1:] {
+		if m[0] != matches[0][0] {
+			return nil, fmt.Errorf("path %s fans out over both %s and %s; only one fan-out is supported per path", relPath, matches[0][0], m[0])
+		}
+	}
+
+	segment, field, want := matches[0][0], matches[0][1], matches[0][2]
+	var projects []map[string]interface{}
+	var err error
+	switch {
+	case field == "":
+		projects, err = allProjects(values["Projects"])
+	case strings.Contains(segment, "="):
+		projects, err = filterProjects(values["Projects"], field, want)
+	default:
+		projects, err = filterProjects(values["Projects"], field)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand %s in %s: %w", segment, relPath, err)
+	}
+
+	instances := make([]fanOutInstance, 0, len(projects))
+	seen := make(map[string]bool, len(projects))
+	for _, project := range projects {
+		name, ok := project["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("failed to expand %s in %s: project has no name", segment, relPath)
+		}
+		// The name becomes a single path segment, so it must not escape or nest the output
+		if name == "." || !filepath.IsLocal(name) || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("failed to expand %s in %s: project name %q is not a valid file name", segment, relPath, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("failed to expand %s in %s: more than one project is named %q", segment, relPath, name)
+		}
+		seen[name] = true
+		instances = append(instances, fanOutInstance{
+			RelPath: strings.ReplaceAll(relPath, segment, name),
+			Project: project,
+		})
+	}
+	return instances, nil
+}
+
+// allProjects returns every project in the Projects value as a map
+func allProjects(projects interface{}) ([]map[string]interface{}, error) {
+	items, err := toSlice(projects)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		project, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected project to be a map, got %T", item)
+		}
+		result = append(result, project)
+	}
+	return result, nil
+}
+
+// withProject returns a shallow copy of values with project bound to .Project
+func withProject(values map[string]interface{}, project map[string]interface{}) map[string]interface{} {
+	bound := make(map[string]interface{}, len(values)+1)
+	for key, val := range values {
+		bound[key] = val
+	}
+	bound[fanOutProjectKey] = project
+	return bound
+}
+
+// fanOutValueKeys records a fanned-out template's .Project references as a
+// dependency on Projects, where the bound project actually comes from
+func fanOutValueKeys(keys []string) []string {
+	result := make([]string, 0, len(keys))
+	hasProjects := false
+	for _, key := range keys {
+		if key == "Projects" {
+			hasProjects = true
+		}
+		if key != fanOutProjectKey {
+			result = append(result, key)
+		}
+	}
+	if !hasProjects {
+		result = append(result, "Projects")
+		sort.Strings(result)
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fanOutTestValues() map[string]interface{} {
+	return map[string]interface{}{
+		"OrgName": "org",
+		"Projects": []map[string]interface{}{
+			{"name": "Alpha", "allowVibeDeploy": true, "language": "go"},
+			{"name": "Beta", "allowVibeDeploy": false, "language": "node"},
+			{"name": "Gamma", "allowVibeDeploy": true, "language": "node"},
+		},
+	}
+}
+
+func TestExpandFanOut(t *testing.T) {
+	tests := []struct {
+		name     string
+		relPath  string
+		expected []string
+	}{
+		{"no fan-out", "__.OrgName__/svc/config.json.tmpl", []string{"__.OrgName__/svc/config.json.tmpl"}},
+		{"all projects", "__each.Projects__/deploy.json.tmpl", []string{"Alpha/deploy.json.tmpl", "Beta/deploy.json.tmpl", "Gamma/deploy.json.tmpl"}},
+		{"truthy filter", "hooks/__each.Projects.allowVibeDeploy__.json.tmpl", []string{"hooks/Alpha.json.tmpl", "hooks/Gamma.json.tmpl"}},
+		{"equality filter", "__each.Projects.language=node__/job.yaml.tmpl", []string{"Beta/job.yaml.tmpl", "Gamma/job.yaml.tmpl"}},
+		{"repeated segment", "__each.Projects.language=go__/__each.Projects.language=go__.env.tmpl", []string{"Alpha/Alpha.env.tmpl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances, err := expandFanOut(tt.relPath, fanOutTestValues())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			paths := make([]string, len(instances))
+			for i, inst := range instances {
+				paths[i] = inst.RelPath
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("expandFanOut(%q) = %v, want %v", tt.relPath, paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpandFanOut_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		relPath string
+		values  map[string]interface{}
+		errMsg  string
+	}{
+		{"different segments", "__each.Projects__/__each.Projects.allowVibeDeploy__.json.tmpl", fanOutTestValues(), "only one fan-out is supported"},
+		{"unnamed project", "__each.Projects__/deploy.json.tmpl", map[string]interface{}{"Projects": []interface{}{map[string]interface{}{"allowVibeDeploy": true}}}, "project has no name"},
+		{"projects not a list", "__each.Projects__/deploy.json.tmpl", map[string]interface{}{"Projects": "nope"}, "expected a list"},
+		{"parent directory name", "__each.Projects__/deploy.json.tmpl", map[string]interface{}{"Projects": []interface{}{map[string]interface{}{"name": "../x"}}}, `project name "../x" is not a valid file name`},
+		{"dot name", "__each.Projects__/deploy.json.tmpl", map[string]interface{}{"Projects": []interface{}{map[string]interface{}{"name": "."}}}, `project name "." is not a valid file name`},
+		{"nested name", "__each.Projects__/deploy.json.tmpl", map[string]interface{}{"Projects": []interface{}{map[string]interface{}{"name": "a/b"}}}, `project name "a/b" is not a valid file name`},
+		{"parent name", "__each.Projects__/deploy.json.tmpl", map[string]interface{}{"Projects": []interface{}{map[string]interface{}{"name": ".."}}}, `project name ".." is not a valid file name`},
+		{"duplicate names", "__each.Projects__/deploy.json.tmpl", map[string]interface{}{"Projects": []interface{}{map[string]interface{}{"name": "A"}, map[string]interface{}{"name": "A"}}}, `more than one project is named "A"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := expandFanOut(tt.relPath, tt.values)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestProcessTemplates_FanOut(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"__.OrgName__/__each.Projects.allowVibeDeploy__/deploy.json.tmpl": `{"org": {{ toJson .OrgName }}, "project": {{ toJson .Project.name }}, "language": {{ toJson .Project.language }}}`,
+	})
+
+	if _, err := processTemplates([]string{sourceDir}, buildDir, fanOutTestValues(), templateOptions{Strict: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, language := range map[string]string{"Alpha": "go", "Gamma": "node"} {
+		data, err := os.ReadFile(filepath.Join(buildDir, "org", name, "deploy.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"org": "org", "project": "` + name + `", "language": "` + language + `"}`
+		if string(data) != expected {
+			t.Errorf("unexpected output for %s: %q", name, string(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "org", "Beta")); !os.IsNotExist(err) {
+		t.Errorf("expected filtered-out project not to be rendered, got err=%v", err)
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("expected 2 manifest entries, got %+v", manifest.Files)
+	}
+	if !reflect.DeepEqual(manifest.Files[0].ValueKeys, []string{"OrgName", "Projects"}) {
+		t.Errorf("expected fan-out to record a dependency on Projects, got %v", manifest.Files[0].ValueKeys)
+	}
+}
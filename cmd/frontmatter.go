@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelimiter opens and closes the optional front-matter block at the top of a template
+const frontMatterDelimiter = "---"
+
+// frontMatter holds the settings a template declares in its front-matter block:
+//
+//	---
+//	mode: "0440"
+//	path: "{{ .Project.name }}.json"
+//	sensitive: true
+//	when: .Project.allowVibeDeploy
+//	---
+type frontMatter struct {
+	// Mode is the octal permission mode of the output file, e.g. "0400"
+	Mode string `yaml:"mode"`
+	// Path overrides the output path. It is itself a template; a relative path is
+	// resolved against the directory of the default output path and a path starting
+	// with "/" against the build directory.
+	Path string `yaml:"path"`
+	// Sensitive marks the output as holding secrets, making it 0400 unless Mode is set
+	Sensitive bool `yaml:"sensitive"`
+	// When is a template pipeline; the template is skipped when it evaluates to false
+	When string `yaml:"when"`
+	// Override replaces an earlier layer's output, like the vibeops:override marker
+	Override bool `yaml:"override"`
+}
+
+// frontMatterKeys are the keys a front-matter block may contain
+var frontMatterKeys = map[string]bool{"mode": true, "path": true, "sensitive": true, "when": true, "override": true}
+
+// splitFrontMatter separates the front-matter block from the template body. The
+// block is replaced with a trimming comment spanning the same number of lines, so
+// line numbers in template errors still match the source file. A leading "---"
+// block is only front matter when it is closed and is a map of front-matter keys,
+// so a YAML template starting with a document marker is left alone. Content
+// without front matter is returned unchanged with a zero frontMatter.
+func splitFrontMatter(content []byte) (frontMatter, []byte, error) {
+	var fm frontMatter
+
+	firstLine, rest, found := bytes.Cut(content, []byte("\n"))
+	if !found || strings.TrimRight(string(firstLine), "\r") != frontMatterDelimiter {
+		return fm, content, nil
+	}
+
+	var block []byte
+	lines := 1
+	for {
+		line, next, found := bytes.Cut(rest, []byte("\n"))
+		if strings.TrimRight(string(line), "\r") == frontMatterDelimiter {
+			lines++
+			if !found {
+				rest = nil
+			} else {
+				rest = next
+			}
+			break
+		}
+		if !found {
+			// Never closed, so this is content rather than front matter
+			return fm, content, nil
+		}
+		block = append(block, line...)
+		block = append(block, '\n')
+		lines++
+		rest = next
+	}
+
+	if !isFrontMatterBlock(block) {
+		return fm, content, nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(block))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fm); err != nil && !errors.Is(err, io.EOF) {
+		return fm, nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+	if _, _, err := fm.fileMode(); err != nil {
+		return fm, nil, err
+	}
+
+	// The comment's left trim removes the newlines standing in for the block
+	body := append([]byte(strings.Repeat("\n", lines)+"{{- /* front matter */}}"), rest...)
+	return fm, body, nil
+}
+
+// isFrontMatterBlock reports whether block is a non-empty YAML map whose keys
+// are all front-matter keys
+func isFrontMatterBlock(block []byte) bool {
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(block, &fields); err != nil || len(fields) == 0 {
+		return false
+	}
+	for key := range fields {
+		if !frontMatterKeys[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// fileMode returns the mode declared in the front matter and whether one was declared
+func (fm frontMatter) fileMode() (os.FileMode, bool, error) {
+	if fm.Mode == "" {
+		return 0, false, nil
+	}
+	mode, err := strconv.ParseUint(fm.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, false, fmt.Errorf("invalid front matter mode %q: expected an octal permission such as \"0400\"", fm.Mode)
+	}
+	return os.FileMode(mode), true, nil
+}
+
+// evalWhen reports whether the when pipeline of the template at srcPath is true
+// for values. An empty expression is always true.
+func (fm frontMatter) evalWhen(srcPath string, values map[string]interface{}, strict bool) (bool, error) {
+	if strings.TrimSpace(fm.When) == "" {
+		return true, nil
+	}
+
+	out, err := executeFrontMatterTemplate(srcPath, "when", "{{ if "+fm.When+" }}true{{ end }}", values, strict)
+	if err != nil {
+		return false, fmt.Errorf("invalid front matter when %q: %w", fm.When, err)
+	}
+	return out == "true", nil
+}
+
+// outputRelPath applies the front-matter path override of the template at srcPath
+// to the default output path. The result must stay inside the build directory.
+func (fm frontMatter) outputRelPath(srcPath, defaultRelPath string, values map[string]interface{}, strict bool) (string, error) {
+	if fm.Path == "" {
+		return defaultRelPath, nil
+	}
+
+	rendered, err := executeFrontMatterTemplate(srcPath, "path", fm.Path, values, strict)
+	if err != nil {
+		return "", fmt.Errorf("invalid front matter path %q: %w", fm.Path, err)
+	}
+
+	relPath := filepath.ToSlash(rendered)
+	if strings.HasPrefix(relPath, "/") {
+		relPath = strings.TrimLeft(relPath, "/")
+	} else {
+		relPath = path.Join(filepath.ToSlash(filepath.Dir(defaultRelPath)), relPath)
+	}
+	relPath = path.Clean(relPath)
+	if relPath == "." || !filepath.IsLocal(relPath) {
+		return "", fmt.Errorf("front matter path %q resolves to %q, which is outside the build directory", fm.Path, relPath)
+	}
+	return filepath.FromSlash(relPath), nil
+}
+
+// executeFrontMatterTemplate renders a front-matter field of the template at
+// srcPath as a template with the template functions. In strict mode every key
+// missing from values is reported, located by the field.
+func executeFrontMatterTemplate(srcPath, name, text string, values map[string]interface{}, strict bool) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if strict {
+		err = executeStrict(tmpl, &buf, srcPath, values)
+		var mkErr *missingKeysError
+		if errors.As(err, &mkErr) {
+			for i := range mkErr.Keys {
+				mkErr.Keys[i].Line, mkErr.Keys[i].Via = 0, "front matter "+name
+			}
+		}
+	} else {
+		err = tmpl.Execute(&buf, values)
+	}
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// valueKeys returns the value keys referenced by the when and path expressions
+func (fm frontMatter) valueKeys() []string {
+	var texts []string
+	if strings.TrimSpace(fm.When) != "" {
+		texts = append(texts, "{{ if "+fm.When+" }}{{ end }}")
+	}
+	if fm.Path != "" {
+		texts = append(texts, fm.Path)
+	}
+
+	var keys []string
+	for _, text := range texts {
+		if tmpl, err := template.New("frontMatter").Funcs(templateFuncs()).Parse(text); err == nil {
+			keys = append(keys, templateValueKeys(tmpl)...)
+		}
+	}
+	return keys
+}
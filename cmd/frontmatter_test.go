@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	content := "---\nmode: \"0440\"\nsensitive: true\nwhen: .Enabled\n---\nKEY={{ .Value }}\n"
+	fm, body, err := splitFrontMatter([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fm.Mode != "0440" || !fm.Sensitive || fm.When != ".Enabled" {
+		t.Errorf("unexpected front matter: %+v", fm)
+	}
+
+	out, err := renderString(t, "body", string(body), map[string]interface{}{"Value": "x"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "KEY=x\n" {
+		t.Errorf("expected front matter to be stripped, got %q", out)
+	}
+
+	// Content without front matter is returned unchanged
+	for _, plain := range []string{
+		"---not front matter\n{{ .Value }}",
+		"---\nmode: \"0400\"\nKEY=value\n",
+		"---\n---\nkind: List\n",
+		"---\nmdoe: \"0400\"\n---\n",
+	} {
+		if fm, body, err := splitFrontMatter([]byte(plain)); err != nil || string(body) != plain || fm != (frontMatter{}) {
+			t.Errorf("expected %q to be unchanged, got %+v %q %v", plain, fm, body, err)
+		}
+	}
+}
+
+func TestSplitFrontMatter_YAMLDocuments(t *testing.T) {
+	content := "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Name }}\n---\napiVersion: v1\nkind: Service\n"
+	fm, body, err := splitFrontMatter([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != content || fm != (frontMatter{}) {
+		t.Errorf("expected the YAML template to be unchanged, got %+v %q", fm, body)
+	}
+
+	out, err := renderString(t, "body", string(body), map[string]interface{}{"Name": "svc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out, "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: svc\n---\n") {
+		t.Errorf("expected the document markers to be kept, got %q", out)
+	}
+}
+
+func TestSplitFrontMatter_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"invalid type", "---\nsensitive: maybe\n---\n", "invalid front matter"},
+		{"invalid mode", "---\nmode: rw\n---\n", "invalid front matter mode"},
+		{"mode out of range", "---\nmode: \"7777\"\n---\n", "invalid front matter mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := splitFrontMatter([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestSplitFrontMatter_PreservesLineNumbers(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "config.yaml.tmpl")
+	if err := os.WriteFile(srcPath, []byte("---\nsensitive: true\n---\nok: true\nbad: {{ .Missing }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := renderTemplateFile(srcPath, map[string]interface{}{}, nil, templateOptions{Strict: true})
+	var missingErr *missingKeysError
+	if !errors.As(err, &missingErr) || len(missingErr.Keys) != 1 {
+		t.Fatalf("expected one missing key, got %v", err)
+	}
+	if missingErr.Keys[0].Line != 5 {
+		t.Errorf("expected missing key reported on line 5, got line %d", missingErr.Keys[0].Line)
+	}
+}
+
+func TestFrontMatterOutputRelPath(t *testing.T) {
+	values := map[string]interface{}{"Name": "svc"}
+	tests := []struct {
+		path     string
+		expected string
+		errMsg   string
+	}{
+		{"", "org/Poppit/.env-builder", ""},
+		{"builder.env", "org/Poppit/builder.env", ""},
+		{"../{{ .Name }}/.env", "org/svc/.env", ""},
+		{"/shared/{{ .Name }}.json", "shared/svc.json", ""},
+		{"../../../escape", "", "outside the build directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := frontMatter{Path: tt.path}.outputRelPath("a.tmpl", filepath.FromSlash("org/Poppit/.env-builder"), values, false)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if filepath.ToSlash(got) != tt.expected {
+				t.Errorf("outputRelPath(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProcessTemplates_FrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"svc/.env-builder.tmpl": "---\nsensitive: true\n---\nPASSWORD={{ .RedisPassword }}",
+		"svc/shared.tmpl":       "---\nmode: \"0640\"\n---\nshared",
+		"svc/renamed.tmpl":      "---\npath: \"/renamed/{{ .Name }}.txt\"\n---\nrenamed",
+		"svc/disabled.tmpl":     "---\nwhen: .Enabled\n---\ndisabled",
+		"__each.Projects__/hook.json.tmpl": "---\nwhen: .Project.allowVibeDeploy\n---\n" +
+			`{"project": {{ toJson .Project.name }}}`,
+	})
+
+	values := map[string]interface{}{
+		"RedisPassword": "secret",
+		"Name":          "svc",
+		"Enabled":       false,
+		"Projects": []map[string]interface{}{
+			{"name": "Alpha", "allowVibeDeploy": true},
+			{"name": "Beta", "allowVibeDeploy": false},
+		},
+	}
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	modes := map[string]os.FileMode{
+		"svc/.env-builder": 0400,
+		"svc/shared":       0640,
+		"renamed/svc.txt":  0644,
+		"Alpha/hook.json":  0644,
+	}
+	for relPath, mode := range modes {
+		info, err := os.Stat(filepath.Join(buildDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			t.Errorf("expected %s to be generated: %v", relPath, err)
+			continue
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("expected %s to have mode %o, got %o", relPath, mode, info.Mode().Perm())
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(buildDir, "svc", ".env-builder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "PASSWORD=secret" {
+		t.Errorf("expected front matter to be stripped, got %q", string(data))
+	}
+
+	for _, relPath := range []string{"svc/disabled", "svc/renamed", "Beta/hook.json"} {
+		if _, err := os.Stat(filepath.Join(buildDir, filepath.FromSlash(relPath))); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be generated, got err=%v", relPath, err)
+		}
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range manifest.Files {
+		if entry.Path == "svc/.env-builder" && !entry.Sensitive {
+			t.Errorf("expected .env-builder to be recorded as sensitive: %+v", entry)
+		}
+		if entry.Path == "svc/shared" && entry.Sensitive {
+			t.Errorf("expected shared not to be recorded as sensitive: %+v", entry)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs returns the function library available to every template.
+// The set is deliberately small and curated: functions are named after their
+// Helm/Sprig equivalents so templates read familiarly, but only helpers that
+// solve a real problem in our configs are included.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// Encoding
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"toYaml":       toYAML,
+		"b64enc":       b64enc,
+		"b64dec":       b64dec,
+		"quote":        quote,
+		"squote":       squote,
+
+		// Defaults and assertions
+		"default":  defaultValue,
+		"required": required,
+		"empty":    isEmpty,
+		"coalesce": coalesce,
+
+		// Strings
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"replace":    func(old, repl, s string) string { return strings.ReplaceAll(s, old, repl) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"indent":     indent,
+		"nindent":    nindent,
+
+		// Collections
+		"list":           list,
+		"dict":           dict,
+		"hasKey":         hasKey,
+		"filterProjects": filterProjects,
+	}
+}
+
+// toJSON encodes v as compact JSON without HTML escaping, so that strings
+// can be dropped directly into JSON templates with correct quoting.
+func toJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// toPrettyJSON encodes v as indented JSON without HTML escaping
+func toPrettyJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return "", fmt.Errorf("toPrettyJson: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// toYAML encodes v as YAML with the trailing newline removed
+func toYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+func b64enc(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(data), nil
+}
+
+// quote wraps each argument in double quotes, escaping it as a JSON string
+// without HTML escaping, so the result is valid in JSON and YAML alike
+func quote(args ...interface{}) string {
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		// Encoding a string cannot fail
+		quoted, _ := toJSON(fmt.Sprint(arg))
+		out = append(out, quoted)
+	}
+	return strings.Join(out, " ")
+}
+
+// squote wraps each argument in single quotes without escaping
+func squote(args ...interface{}) string {
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		out = append(out, "'"+fmt.Sprint(arg)+"'")
+	}
+	return strings.Join(out, " ")
+}
+
+// isEmpty reports whether v is nil or the zero value of its type
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+// defaultValue returns def when given is missing or empty.
+// It is used as {{ .Key | default "fallback" }}.
+func defaultValue(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || isEmpty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+// required fails template execution with msg when v is missing or empty
+func required(msg string, v interface{}) (interface{}, error) {
+	if isEmpty(v) {
+		return nil, fmt.Errorf("%s", msg)
+	}
+	return v, nil
+}
+
+// coalesce returns the first non-empty argument
+func coalesce(args ...interface{}) interface{} {
+	for _, arg := range args {
+		if !isEmpty(arg) {
+			return arg
+		}
+	}
+	return nil
+}
+
+// join concatenates the elements of a list with sep
+func join(sep string, v interface{}) (string, error) {
+	items, err := toSlice(v)
+	if err != nil {
+		return "", fmt.Errorf("join: %w", err)
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// indent prefixes every line of s with the given number of spaces
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// nindent is indent preceded by a newline, for use at the end of a YAML key
+func nindent(spaces int, s string) string {
+	return "\n" + indent(spaces, s)
+}
+
+func list(items ...interface{}) []interface{} {
+	return items
+}
+
+// dict builds a map from alternating key/value arguments
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected an even number of arguments, got %d", len(pairs))
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key at position %d is %T, not a string", i, pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// hasKey reports whether the map m contains key
+func hasKey(m interface{}, key string) (bool, error) {
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return false, fmt.Errorf("hasKey: expected a map with string keys, got %T", m)
+	}
+	return rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).IsValid(), nil
+}
+
+// filterProjects returns the projects whose field is truthy, or equal to want
+// when it is given. For example:
+//
+//	{{ range filterProjects .Projects "useWithSlackCompose" }}
+//	{{ range filterProjects .Projects "name" "VibeOps" }}
+func filterProjects(projects interface{}, field string, want ...interface{}) ([]map[string]interface{}, error) {
+	items, err := toSlice(projects)
+	if err != nil {
+		return nil, fmt.Errorf("filterProjects: %w", err)
+	}
+	if len(want) > 1 {
+		return nil, fmt.Errorf("filterProjects: expected at most one value to compare %q against, got %d", field, len(want))
+	}
+	filtered := []map[string]interface{}{}
+	for _, item := range items {
+		project, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("filterProjects: expected project to be a map, got %T", item)
+		}
+		value := project[field]
+		if len(want) == 1 {
+			if fmt.Sprint(value) == fmt.Sprint(want[0]) && value != nil {
+				filtered = append(filtered, project)
+			}
+			continue
+		}
+		if truth, _ := template.IsTrue(value); truth {
+			filtered = append(filtered, project)
+		}
+	}
+	return filtered, nil
+}
+
+// toSlice converts any slice or array value to []interface{}
+func toSlice(v interface{}) ([]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, got %T", v)
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// renderString parses text with the template function library and executes it
+func renderString(t *testing.T, name, text string, values interface{}) (string, error) {
+	t.Helper()
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Parse(text)
+	if err != nil {
+		t.Fatalf("failed to parse template %s: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	values := map[string]interface{}{
+		"Name":    "VibeOps",
+		"Empty":   "",
+		"List":    []interface{}{"a", "b", "c"},
+		"Quoted":  `say "hi"`,
+		"Map":     map[string]interface{}{"key": "value"},
+		"Secret":  "hunter2",
+		"Encoded": "aHVudGVyMg==",
+		"Nested":  map[string]interface{}{"host": "localhost", "port": 6379},
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"toJson string", `{{ toJson .Quoted }}`, `"say \"hi\""`},
+		{"toJson map", `{{ toJson .Map }}`, `{"key":"value"}`},
+		{"toJson no html escaping", `{{ toJson "<a&b>" }}`, `"<a&b>"`},
+		{"toPrettyJson", `{{ toPrettyJson .Map }}`, "{\n  \"key\": \"value\"\n}"},
+		{"toYaml", `{{ toYaml .Nested }}`, "host: localhost\nport: 6379"},
+		{"quote", `{{ quote .Quoted }}`, `"say \"hi\""`},
+		{"quote as JSON", `{{ quote "<a&b>\x01é" 6379 }}`, `"<a&b>\u0001é" "6379"`},
+		{"squote", `{{ squote .Name }}`, `'VibeOps'`},
+		{"default used", `{{ .Empty | default "fallback" }}`, "fallback"},
+		{"default missing key", `{{ .Missing | default "fallback" }}`, "fallback"},
+		{"default not used", `{{ .Name | default "fallback" }}`, "VibeOps"},
+		{"coalesce", `{{ coalesce .Missing .Empty .Name }}`, "VibeOps"},
+		{"empty", `{{ empty .Empty }} {{ empty .Name }}`, "true false"},
+		{"required present", `{{ required "Name is required" .Name }}`, "VibeOps"},
+		{"join", `{{ join "," .List }}`, "a,b,c"},
+		{"split", `{{ index (split "," "x,y") 1 }}`, "y"},
+		{"indent", `{{ indent 2 "a\nb" }}`, "  a\n  b"},
+		{"nindent", `key:{{ toYaml .Nested | nindent 2 }}`, "key:\n  host: localhost\n  port: 6379"},
+		{"b64enc", `{{ b64enc .Secret }}`, "aHVudGVyMg=="},
+		{"b64dec", `{{ b64dec .Encoded }}`, "hunter2"},
+		{"lower", `{{ lower .Name }}`, "vibeops"},
+		{"upper", `{{ upper .Name }}`, "VIBEOPS"},
+		{"trim", `{{ trim "  x  " }}`, "x"},
+		{"trimSuffix", `{{ trimSuffix ".json" "config.json" }}`, "config"},
+		{"replace", `{{ replace "-" "_" "a-b-c" }}`, "a_b_c"},
+		{"contains", `{{ contains "Ops" .Name }}`, "true"},
+		{"hasKey", `{{ hasKey .Map "key" }} {{ hasKey .Map "nope" }}`, "true false"},
+		{"hasKey on values", `{{ hasKey . "Name" }}`, "true"},
+		{"list and join", `{{ join "-" (list 1 2 3) }}`, "1-2-3"},
+		{"dict and toJson", `{{ toJson (dict "a" 1) }}`, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderString(t, tt.name, tt.text, values)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncs_Errors(t *testing.T) {
+	values := map[string]interface{}{"Empty": "", "Name": "x"}
+
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"required missing", `{{ required "RedisHost must be set" .RedisHost }}`, "RedisHost must be set"},
+		{"required empty", `{{ required "Empty must be set" .Empty }}`, "Empty must be set"},
+		{"dict odd args", `{{ dict "a" }}`, "even number of arguments"},
+		{"hasKey on string", `{{ hasKey .Name "x" }}`, "expected a map"},
+		{"join non-list", `{{ join "," .Name }}`, "expected a list"},
+		{"b64dec invalid", `{{ b64dec "!!" }}`, "b64dec"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderString(t, tt.name, tt.text, values)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFilterProjects(t *testing.T) {
+	projects := []map[string]interface{}{
+		{"name": "A", "useWithSlackCompose": true, "portKey": "APort"},
+		{"name": "B", "useWithSlackCompose": false},
+		{"name": "C", "useWithSlackCompose": true},
+	}
+
+	filtered, err := filterProjects(projects, "useWithSlackCompose")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filtered) != 2 || filtered[0]["name"] != "A" || filtered[1]["name"] != "C" {
+		t.Errorf("unexpected truthy filter result: %v", filtered)
+	}
+
+	filtered, err = filterProjects(projects, "name", "B")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0]["name"] != "B" {
+		t.Errorf("unexpected equality filter result: %v", filtered)
+	}
+
+	filtered, err = filterProjects(nil, "name")
+	if err != nil || len(filtered) != 0 {
+		t.Errorf("expected empty result for nil projects, got %v (err %v)", filtered, err)
+	}
+}
+
+// Legacy versions of the stock templates, before they were rewritten to use the
+// function library. They are kept here to prove the rewrite is output-compatible.
+const (
+	legacySlackComposeTmpl = `[
+  {{- $first := true }}
+  {{- range $i, $p := .Projects }}
+    {{- if $p.useWithSlackCompose }}{{if not $first}},{{end}}
+    {
+      "name": "{{$p.name}}",
+      "working_dir": "{{$.BaseDir}}/{{$.OrgName}}/{{$p.name}}"
+    }
+    {{- $first = false }}{{end}}
+  {{- end }}
+]
+`
+
+	legacyOctoCatalogTmpl = `[
+  {
+    "actionId": "SlackCompose",
+    "options": [
+      {{- $first := true }}
+      {{- range $i, $p := .Projects }}
+        {{- if $p.useWithSlackCompose }}{{if not $first}},{{end}}
+        {
+          "text": "{{$p.name}}",
+          "value": "{{$p.name}}"
+        }
+        {{- $first = false }}{{end}}
+      {{- end }}
+    ]
+  },
+  {
+    "actionId": "SlashVibeIssue",
+    "options": [
+      {{- $first := true }}
+      {{- range $i, $p := .Projects }}
+        {{- if $p.useWithGitHubIssue }}{{if not $first}},{{end}}
+        {
+          "text": "{{$p.name}}",
+          "value": "{{$p.name}}"
+        }
+        {{- $first = false }}{{end}}
+      {{- end }}
+    ]
+  }
+]
+`
+
+	legacyGithubDispatcherTmpl = `[
+{{- range $i, $p := .Projects }}
+  {{- if gt $i 0 }},{{ end }}
+  {
+    "repo": "{{$.OrgName}}/{{ $p.name }}",
+    "branch": "refs/heads/main",
+    "type": "github-dispatcher",
+    "dir": "{{$.BaseDir}}/{{$.OrgName}}/{{ $p.name }}",
+    "commands": [
+      {{- if $p.buildCommands }}{{- range $j, $cmd := $p.buildCommands }}
+      {{- if gt $j 0 }},{{ end }}
+      "{{ $cmd }}" {{- end }}
+      {{- else if $p.isDockerProject }}
+      {{- if $p.isGitHubActionsManaged }}
+      "git checkout main",
+      "git pull"
+      {{- else }}
+      "git checkout main",
+      "git pull",
+      "docker compose build",
+      "docker compose down",
+      "docker compose up -d"
+      {{- end }}
+      {{- end }}
+    ]
+  }
+{{- end }}
+]
+`
+
+	legacyThisIsFineTmpl = `{
+  "port": {{ or .ThisIsFinePort "0" }},
+  "dockerServices": [
+  {{- $first := true }}
+  {{- range $i, $p := .Projects }}
+    {{- if $p.isDockerProject }}{{if not $first}},{{end}}
+    "{{$.OrgName}}/{{$p.name}}"
+    {{- $first = false }}{{end}}
+  {{- end }}
+  ],
+  "systemdServices": [
+    "poppit",
+    "poppit-builder",
+    "thisisfine"
+  ]
+}
+`
+)
+
+// stockTemplateValues returns values based on the example files in the repository root
+func stockTemplateValues(t *testing.T) map[string]interface{} {
+	t.Helper()
+	values, err := utils.LoadValuesFromFile(filepath.Join("..", "values.json.example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects, err := utils.LoadProjectsMap(filepath.Join("..", "projects.json.example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values["Projects"] = projects
+	values["ThisIsFinePort"] = 8090
+	return values
+}
+
+var stockTemplates = []struct {
+	path   string
+	legacy string
+}{
+	{"SlackCompose/projects.json.tmpl", legacySlackComposeTmpl},
+	{"OctoCatalog/catalog.json.tmpl", legacyOctoCatalogTmpl},
+	{"github-dispatcher/config.json.tmpl", legacyGithubDispatcherTmpl},
+	{"ThisIsFine/config.json.tmpl", legacyThisIsFineTmpl},
+}
+
+func TestStockTemplates_MatchLegacyOutput(t *testing.T) {
+	values := stockTemplateValues(t)
+
+	for _, tt := range stockTemplates {
+		t.Run(tt.path, func(t *testing.T) {
+			current, err := os.ReadFile(filepath.Join("..", "source", "__.OrgName__", tt.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := renderString(t, "legacy", tt.legacy, values)
+			if err != nil {
+				t.Fatalf("legacy template failed: %v", err)
+			}
+			got, err := renderString(t, tt.path, string(current), values)
+			if err != nil {
+				t.Fatalf("stock template failed: %v", err)
+			}
+			if got != want {
+				t.Errorf("stock template output differs from legacy output\n--- got ---\n%s\n--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestStockTemplates_EscapeSpecialCharacters(t *testing.T) {
+	values := stockTemplateValues(t)
+	values["BaseDir"] = `C:\vibe "ops"`
+	values["Projects"] = []map[string]interface{}{
+		{
+			"name":                `Quote"Project`,
+			"useWithSlackCompose": true,
+			"useWithGitHubIssue":  true,
+			"isDockerProject":     true,
+		},
+		{
+			"name":                "Back\\slash",
+			"useWithSlackCompose": true,
+			"useWithGitHubIssue":  true,
+			"buildCommands":       []interface{}{`echo "done" && exit 0`, "printf '%s\\n' <tab>"},
+		},
+	}
+
+	for _, tt := range stockTemplates {
+		t.Run(tt.path, func(t *testing.T) {
+			current, err := os.ReadFile(filepath.Join("..", "source", "__.OrgName__", tt.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := renderString(t, tt.path, string(current), values)
+			if err != nil {
+				t.Fatalf("stock template failed: %v", err)
+			}
+			if err := utils.ValidateJSON([]byte(got), tt.path); err != nil {
+				t.Fatalf("stock template produced invalid JSON: %v\n%s", err, got)
+			}
+
+			// The legacy template interpolates raw strings and must produce invalid JSON
+			legacy, err := renderString(t, "legacy", tt.legacy, values)
+			if err != nil {
+				t.Fatalf("legacy template failed: %v", err)
+			}
+			if utils.ValidateJSON([]byte(legacy), tt.path) == nil {
+				t.Errorf("expected legacy template to produce invalid JSON for special characters")
+			}
+		})
+	}
+}
+
+func TestStockTemplates_RoundTripProjectNames(t *testing.T) {
+	values := stockTemplateValues(t)
+	name := `Quote"Back\\slash<&>`
+	values["Projects"] = []map[string]interface{}{
+		{"name": name, "useWithSlackCompose": true},
+	}
+
+	current, err := os.ReadFile(filepath.Join("..", "source", "__.OrgName__", "SlackCompose", "projects.json.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderString(t, "projects.json.tmpl", string(current), values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed []map[string]string
+	if err := json.Unmarshal([]byte(got), &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, got)
+	}
+	if len(parsed) != 1 || parsed[0]["name"] != name {
+		t.Errorf("expected project name %q to round-trip, got %v", name, parsed)
+	}
+}
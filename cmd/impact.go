@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewImpactCmd creates the impact command
+func NewImpactCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "impact --key KEY [--key KEY ...]",
+		Short: "List the services affected by changing value keys",
+		Long: `Map value keys to the templates that reference them and then to the service
+directories those templates render into, listing the services that need a restart
+when the keys change. With --restart, the affected services are restarted through
+TurnItOffAndOnAgain as vibeops diff does, without needing a prev-build snapshot.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys, _ := cmd.Flags().GetStringArray("key")
+			sourceDirs, _ := cmd.Flags().GetStringSlice("source-dir")
+			followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+			restart, _ := cmd.Flags().GetBool("restart")
+			configFile, _ := cmd.Flags().GetString("config")
+
+			if len(keys) == 0 {
+				return fmt.Errorf("at least one --key is required")
+			}
+
+			// Load the restart configuration up front so a bad config fails before any analysis
+			var config *utils.TurnItOffAndOnAgainConfig
+			if restart {
+				var err error
+				config, err = utils.LoadTurnItOffAndOnAgainConfig(configFile)
+				if err != nil {
+					return fmt.Errorf("error loading config: %w", err)
+				}
+			}
+
+			deps, err := analyzeTemplateDeps(sourceDirs, followSymlinks)
+			if err != nil {
+				return fmt.Errorf("error analysing templates: %w", err)
+			}
+			references, err := loadImpactReferences()
+			if err != nil {
+				return err
+			}
+			impact, err := analyzeImpact(deps, keys, references, loadImpactProjects)
+			if err != nil {
+				return err
+			}
+
+			for _, key := range keys {
+				if dependents := impact.Dependents[key]; len(dependents) > 0 {
+					fmt.Printf("Key %s is also used through %s\n", key, strings.Join(dependents, ", "))
+				}
+				templates := impact.Templates[key]
+				if len(templates) == 0 {
+					fmt.Printf("Key %s is not referenced by any template\n", key)
+					continue
+				}
+				fmt.Printf("Key %s is referenced by %d template(s):\n", key, len(templates))
+				for _, path := range templates {
+					fmt.Printf("  - %s\n", path)
+				}
+			}
+
+			if len(impact.Services) == 0 {
+				fmt.Println("No services are affected")
+				return nil
+			}
+			fmt.Printf("Affected service(s) (%d):\n", len(impact.Services))
+			for _, service := range impact.Services {
+				fmt.Printf("  - %s\n", service)
+			}
+
+			if !restart {
+				return nil
+			}
+			if err := restartServices(impact.Services, config); err != nil {
+				return fmt.Errorf("error restarting services: %w", err)
+			}
+			fmt.Println("All services restarted successfully!")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayP("key", "k", nil, "Value key to analyse (repeatable)")
+	cmd.Flags().StringSliceP("source-dir", "s", []string{"source"}, "Source directories to analyse, in layer order")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symlinks in the source directories")
+	cmd.Flags().Bool("restart", false, "Restart the affected services via TurnItOffAndOnAgain")
+	cmd.Flags().StringP("config", "c", "config.json", "Path to TurnItOffAndOnAgain configuration file")
+	return cmd
+}
+
+// keyImpact is the result of an impact analysis
+type keyImpact struct {
+	// Templates maps each analysed key to the sorted paths of the templates
+	// referencing it or one of its dependents
+	Templates map[string][]string
+	// Dependents maps each analysed key to the sorted keys whose values reference
+	// it with ${Key}, directly or through other values
+	Dependents map[string][]string
+	// Services are the sorted service directories those templates render into
+	Services []string
+}
+
+// analyzeImpact maps keys to the templates referencing them and to the services
+// those templates belong to. A template's service is the directory below the
+// organisation directory, e.g. __.OrgName__/Poppit/.env.tmpl belongs to Poppit;
+// templates outside a service directory affect no service. A __each.Projects__
+// service directory affects one service per matching project, so loadProjects is
+// called, at most once, when one is found. references maps each value key to
+// the keys its value references, so a change to RedisHost also reaches the
+// templates using a RedisAddr of "${RedisHost}:6379".
+func analyzeImpact(deps []templateDeps, keys []string, references map[string][]string, loadProjects func() ([]map[string]interface{}, error)) (*keyImpact, error) {
+	byKey := keyTemplates(deps)
+	referencedBy := make(map[string][]string)
+	for key, refs := range references {
+		for _, ref := range refs {
+			referencedBy[ref] = append(referencedBy[ref], key)
+		}
+	}
+
+	impact := &keyImpact{Templates: make(map[string][]string, len(keys)), Dependents: make(map[string][]string)}
+	affected := make(map[string]bool)
+	for _, key := range keys {
+		dependents := dependentKeys(key, referencedBy)
+		if len(dependents) > 0 {
+			impact.Dependents[key] = dependents
+		}
+
+		templates := make(map[string]bool)
+		for _, k := range append([]string{key}, dependents...) {
+			for _, path := range byKey[k] {
+				templates[path] = true
+			}
+		}
+		var paths []string
+		for path := range templates {
+			paths = append(paths, path)
+			affected[path] = true
+		}
+		sort.Strings(paths)
+		impact.Templates[key] = paths
+	}
+
+	var projectValues map[string]interface{}
+	serviceSet := make(map[string]bool)
+	for _, d := range deps {
+		if !affected[d.Path] {
+			continue
+		}
+		service := serviceFromPath(d.RelPath)
+		if service == "" {
+			continue
+		}
+		if !fanOutPattern.MatchString(service) {
+			serviceSet[service] = true
+			continue
+		}
+
+		if projectValues == nil {
+			projects, err := loadProjects()
+			if err != nil {
+				return nil, fmt.Errorf("error loading projects.json: %w", err)
+			}
+			projectValues = map[string]interface{}{"Projects": projects}
+		}
+		instances, err := expandFanOut(service, projectValues)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", filepath.ToSlash(d.RelPath), err)
+		}
+		for _, inst := range instances {
+			serviceSet[inst.RelPath] = true
+		}
+	}
+
+	impact.Services = make([]string, 0, len(serviceSet))
+	for service := range serviceSet {
+		impact.Services = append(impact.Services, service)
+	}
+	sort.Strings(impact.Services)
+	return impact, nil
+}
+
+// dependentKeys returns the sorted keys whose values reference key, directly or
+// through other values, given the keys referencing each key
+func dependentKeys(key string, referencedBy map[string][]string) []string {
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+	var dependents []string
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range referencedBy[current] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				dependents = append(dependents, dependent)
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// loadImpactReferences loads the ${Key} references of the values and ports
+// files, the only files whose values are interpolated, keyed by the referencing key
+func loadImpactReferences() (map[string][]string, error) {
+	references := make(map[string][]string)
+	for _, name := range []string{"values", "ports"} {
+		file, err := utils.FindValuesFile(name)
+		if err != nil {
+			return nil, err
+		}
+		values, err := utils.LoadValuesFromFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s: %w", file, err)
+		}
+		for key, refs := range valueReferences(values, nil) {
+			references[key] = append(references[key], refs...)
+		}
+	}
+	return references, nil
+}
+
+// loadImpactProjects loads projects.json for expanding fanned-out service directories
+func loadImpactProjects() ([]map[string]interface{}, error) {
+	projectsFile, err := utils.FindValuesFile("projects")
+	if err != nil {
+		return nil, err
+	}
+	return utils.LoadProjectsMap(projectsFile)
+}
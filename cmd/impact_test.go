@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeImpact(t *testing.T) {
+	deps := []templateDeps{
+		{Path: "source/__.OrgName__/Poppit/.env.tmpl", RelPath: "__.OrgName__/Poppit/.env.tmpl", Keys: []string{"OrgName", "RedisPassword"}},
+		{Path: "source/__.OrgName__/OctoSlack/.env.tmpl", RelPath: "__.OrgName__/OctoSlack/.env.tmpl", Keys: []string{"OrgName", "RedisPassword", "SlackBotToken"}},
+		{Path: "source/__.OrgName__/Renobot/config.yaml.tmpl", RelPath: "__.OrgName__/Renobot/config.yaml.tmpl", Keys: []string{"PoppitListName"}},
+		{Path: "source/__.OrgName__/__each.Projects.allowVibeDeploy__/deploy.json.tmpl", RelPath: "__.OrgName__/__each.Projects.allowVibeDeploy__/deploy.json.tmpl", Keys: []string{"Projects", "SlackBotToken"}},
+		{Path: "source/README.txt.tmpl", RelPath: "README.txt.tmpl", Keys: []string{"RedisPassword"}},
+		{Path: "source/__.OrgName__/Cache/config.json.tmpl", RelPath: "__.OrgName__/Cache/config.json.tmpl", Keys: []string{"CacheURL"}},
+	}
+	references := map[string][]string{"RedisAddr": {"RedisHost"}, "CacheURL": {"RedisAddr", "OrgName"}}
+	loads := 0
+	loadProjects := func() ([]map[string]interface{}, error) {
+		loads++
+		return []map[string]interface{}{
+			{"name": "Alpha", "allowVibeDeploy": true},
+			{"name": "Beta", "allowVibeDeploy": false},
+		}, nil
+	}
+
+	tests := []struct {
+		name       string
+		keys       []string
+		services   []string
+		templates  map[string][]string
+		dependents map[string][]string
+		loads      int
+	}{
+		{
+			name:     "single key",
+			keys:     []string{"RedisPassword"},
+			services: []string{"OctoSlack", "Poppit"},
+			templates: map[string][]string{"RedisPassword": {
+				"source/README.txt.tmpl",
+				"source/__.OrgName__/OctoSlack/.env.tmpl",
+				"source/__.OrgName__/Poppit/.env.tmpl",
+			}},
+		},
+		{
+			name:     "fan-out service",
+			keys:     []string{"SlackBotToken", "PoppitListName"},
+			services: []string{"Alpha", "OctoSlack", "Renobot"},
+			templates: map[string][]string{
+				"SlackBotToken":  {"source/__.OrgName__/OctoSlack/.env.tmpl", "source/__.OrgName__/__each.Projects.allowVibeDeploy__/deploy.json.tmpl"},
+				"PoppitListName": {"source/__.OrgName__/Renobot/config.yaml.tmpl"},
+			},
+			loads: 1,
+		},
+		{
+			name:      "unused key",
+			keys:      []string{"Nope"},
+			services:  []string{},
+			templates: map[string][]string{"Nope": nil},
+		},
+		{
+			name:       "referenced by other values",
+			keys:       []string{"RedisHost"},
+			services:   []string{"Cache"},
+			templates:  map[string][]string{"RedisHost": {"source/__.OrgName__/Cache/config.json.tmpl"}},
+			dependents: map[string][]string{"RedisHost": {"CacheURL", "RedisAddr"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loads = 0
+			impact, err := analyzeImpact(deps, tt.keys, references, loadProjects)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(impact.Services, tt.services) {
+				t.Errorf("Services = %v, want %v", impact.Services, tt.services)
+			}
+			if !reflect.DeepEqual(impact.Templates, tt.templates) {
+				t.Errorf("Templates = %v, want %v", impact.Templates, tt.templates)
+			}
+			if len(impact.Dependents) > 0 || tt.dependents != nil {
+				if !reflect.DeepEqual(impact.Dependents, tt.dependents) {
+					t.Errorf("Dependents = %v, want %v", impact.Dependents, tt.dependents)
+				}
+			}
+			if loads != tt.loads {
+				t.Errorf("projects loaded %d time(s), want %d", loads, tt.loads)
+			}
+		})
+	}
+}
+
+func TestAnalyzeImpact_ProjectsError(t *testing.T) {
+	deps := []templateDeps{
+		{Path: "source/org/__each.Projects__/a.tmpl", RelPath: "org/__each.Projects__/a.tmpl", Keys: []string{"Projects"}},
+	}
+	_, err := analyzeImpact(deps, []string{"Projects"}, nil, func() ([]map[string]interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "error loading projects.json: boom") {
+		t.Fatalf("expected a projects.json error, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// overrideMarkerPattern matches the {{/* vibeops:override */}} comment that lets a
+// template in a later source layer intentionally replace an earlier layer's output
+var overrideMarkerPattern = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*vibeops:override\s*\*/\s*-?\}\}`)
+
+// templateSource is a template discovered in one of the source layers
+type templateSource struct {
+	// Layer is the source directory the template was found in
+	Layer string
+	// Path is the template file path and RelPath is relative to Layer
+	Path    string
+	RelPath string
+	// OutputRelPath is the expanded output path relative to the build directory
+	OutputRelPath string
+	// Override reports whether the template carries the override marker
+	Override bool
+	// Project is bound to .Project when the template is fanned out over Projects
+	Project map[string]interface{}
+	// ServiceDir is the build-relative service directory the template is in, bound
+	// to .Service and .ServiceDir, or "" outside one
+	ServiceDir string
+	// Content is the raw template, read once during discovery
+	Content []byte
+}
+
+// hasOverrideMarker reports whether template content starts with the override marker
+func hasOverrideMarker(content []byte) bool {
+	return overrideMarkerPattern.Match(content)
+}
+
+// collectTemplates walks every source layer in order and returns the templates
+// found in them along with the expanded directories to create in the build.
+// Partials are skipped; they are loaded separately by loadPartials. In strict
+// mode a template whose front matter fails is added to report and skipped.
+func collectTemplates(sourceDirs []string, values map[string]interface{}, opts templateOptions, report *strictReport) ([]templateSource, []string, error) {
+	var sources []templateSource
+	var dirs []string
+
+	for _, sourceDir := range sourceDirs {
+		walkFn := func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Skip the source directory itself
+			if path == sourceDir {
+				return nil
+			}
+
+			// Calculate relative path from source directory
+			relPath, err := filepath.Rel(sourceDir, path)
+			if err != nil {
+				return fmt.Errorf("failed to get relative path: %w", err)
+			}
+
+			// Partials are only ever included by other templates, never emitted
+			if isPartialPath(relPath) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Expand __each.Projects__ segments once per project
+			instances, err := expandFanOut(relPath, values)
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				for _, inst := range instances {
+					// Expand __.Key__ placeholders in the relative path
+					dirs = append(dirs, expandPathVars(inst.RelPath, values))
+				}
+				return nil
+			}
+
+			// Only process .tmpl files
+			if !strings.HasSuffix(path, ".tmpl") {
+				return nil
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read template file %s: %w", path, err)
+			}
+			fm, _, err := splitFrontMatter(content)
+			if err != nil {
+				return fmt.Errorf("failed to process template %s: %w", path, err)
+			}
+
+			for _, inst := range instances {
+				instValues := values
+				if inst.Project != nil {
+					instValues = withProject(values, inst.Project)
+				}
+				expandedRelPath := expandPathVars(inst.RelPath, values)
+				serviceDir := serviceDirOf(expandedRelPath)
+				if serviceDir != "" {
+					instValues = withService(instValues, serviceDir)
+				}
+
+				// Templates whose front matter "when" is false are not rendered at all
+				render, err := fm.evalWhen(path, instValues, opts.Strict)
+				var outputRelPath string
+				if err == nil && render {
+					outputRelPath, err = fm.outputRelPath(path, strings.TrimSuffix(expandedRelPath, ".tmpl"), instValues, opts.Strict)
+				}
+				if err != nil {
+					err = fmt.Errorf("failed to process template %s: %w", path, err)
+					if !opts.Strict {
+						return err
+					}
+					// In strict mode the failure is reported along with every other one
+					report.Add(err)
+					continue
+				}
+				if !render {
+					continue
+				}
+
+				sources = append(sources, templateSource{
+					Layer:         sourceDir,
+					Path:          path,
+					RelPath:       relPath,
+					OutputRelPath: outputRelPath,
+					Override:      fm.Override || hasOverrideMarker(content),
+					Project:       inst.Project,
+					ServiceDir:    serviceDir,
+					Content:       content,
+				})
+			}
+			return nil
+		}
+
+		// Walk through the source directory, optionally following symlinks
+		var err error
+		if opts.FollowSymlinks {
+			err = walkDirFollowSymlinks(sourceDir, walkFn)
+		} else {
+			err = filepath.WalkDir(sourceDir, walkFn)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return sources, dirs, nil
+}
+
+// layerConflict describes two templates that generate the same output path
+type layerConflict struct {
+	OutputRelPath string
+	First         string
+	Second        string
+}
+
+// layerConflictError reports every unintended output collision between templates
+type layerConflictError struct {
+	Conflicts []layerConflict
+}
+
+func (e *layerConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d output path(s) generated by more than one template (mark the later template with {{/* vibeops:override */}} to replace the earlier one):", len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		fmt.Fprintf(&b, "\n  %s: %s and %s", c.OutputRelPath, c.First, c.Second)
+	}
+	return b.String()
+}
+
+// resolveLayers picks the template that generates each output path. A template
+// in a later layer replaces an earlier layer's template for the same output only
+// when it carries the override marker; any other collision, including two
+// templates in the same layer, is reported as a layerConflictError.
+func resolveLayers(sources []templateSource) ([]templateSource, error) {
+	winners := make(map[string]int)
+	resolved := make([]templateSource, 0, len(sources))
+	var conflicts []layerConflict
+
+	for _, src := range sources {
+		i, exists := winners[src.OutputRelPath]
+		if !exists {
+			winners[src.OutputRelPath] = len(resolved)
+			resolved = append(resolved, src)
+			continue
+		}
+
+		previous := resolved[i]
+		if src.Override && src.Layer != previous.Layer {
+			fmt.Printf("Overriding %s with %s\n", previous.Path, src.Path)
+			resolved[i] = src
+			continue
+		}
+		conflicts = append(conflicts, layerConflict{
+			OutputRelPath: filepath.ToSlash(src.OutputRelPath),
+			First:         previous.Path,
+			Second:        src.Path,
+		})
+	}
+
+	if len(conflicts) > 0 {
+		sort.Slice(conflicts, func(i, j int) bool {
+			return conflicts[i].OutputRelPath < conflicts[j].OutputRelPath
+		})
+		return nil, &layerConflictError{Conflicts: conflicts}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHasOverrideMarker(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected bool
+	}{
+		{"{{/* vibeops:override */}}\nkey: value", true},
+		{"{{- /* vibeops:override */ -}}\nkey: value", true},
+		{"  {{/*vibeops:override*/}}", true},
+		{"key: value\n{{/* vibeops:override */}}", false},
+		{"{{/* some other comment */}}", false},
+	}
+	for _, tt := range tests {
+		if got := hasOverrideMarker([]byte(tt.content)); got != tt.expected {
+			t.Errorf("hasOverrideMarker(%q) = %v, want %v", tt.content, got, tt.expected)
+		}
+	}
+}
+
+func TestProcessTemplates_LayerOverride(t *testing.T) {
+	dir := t.TempDir()
+	publicDir := filepath.Join(dir, "source")
+	privateDir := filepath.Join(dir, "source-private")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, publicDir, map[string]string{
+		"svc/config.yaml.tmpl": "owner: public",
+		"svc/public.yaml.tmpl": "public: true",
+	})
+	writeTestFiles(t, privateDir, map[string]string{
+		"svc/config.yaml.tmpl":  "{{- /* vibeops:override */ -}}\nowner: private",
+		"svc/private.yaml.tmpl": "private: true",
+	})
+
+	if _, err := processTemplates([]string{publicDir, privateDir}, buildDir, map[string]interface{}{}, templateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(buildDir, "svc", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "owner: private" {
+		t.Errorf("expected private layer to override, got %q", string(data))
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := make(map[string]string)
+	for _, entry := range manifest.Files {
+		layers[entry.Path] = entry.Layer
+	}
+	expected := map[string]string{
+		"svc/config.yaml":  filepath.ToSlash(privateDir),
+		"svc/public.yaml":  filepath.ToSlash(publicDir),
+		"svc/private.yaml": filepath.ToSlash(privateDir),
+	}
+	for path, layer := range expected {
+		if layers[path] != layer {
+			t.Errorf("expected %s to come from %s, got %q", path, layer, layers[path])
+		}
+	}
+}
+
+func TestProcessTemplates_LayerConflict(t *testing.T) {
+	dir := t.TempDir()
+	publicDir := filepath.Join(dir, "source")
+	privateDir := filepath.Join(dir, "source-private")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, publicDir, map[string]string{
+		"svc/config.yaml.tmpl":       "owner: public",
+		"__.Service__/env.yaml.tmpl": "a: 1",
+		"svc/env.yaml.tmpl":          "a: 2",
+	})
+	writeTestFiles(t, privateDir, map[string]string{
+		"svc/config.yaml.tmpl": "owner: private",
+	})
+
+	values := map[string]interface{}{"Service": "svc"}
+	_, err := processTemplates([]string{publicDir, privateDir}, buildDir, values, templateOptions{})
+
+	var conflictErr *layerConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected layerConflictError, got %v", err)
+	}
+	if len(conflictErr.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflictErr.Conflicts)
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"svc/config.yaml: " + filepath.Join(publicDir, "svc", "config.yaml.tmpl") + " and " + filepath.Join(privateDir, "svc", "config.yaml.tmpl"),
+		"svc/env.yaml: ",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected error to contain %q, got:\n%s", want, msg)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(buildDir, "svc", "config.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written on conflict, got err=%v", err)
+	}
+}
@@ -15,14 +15,36 @@ func NewLinkCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "link",
 		Short: "Create symlinks from build directory to BaseDir",
-		Long:  `Walk through the build directory and create symlinks to the BaseDir specified in values.json.`,
+		Long:  `Walk through the build directory and create symlinks to the BaseDir specified in values.json (or values.<env>.json with --env).`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			buildDir, _ := cmd.Flags().GetString("build-dir")
+			env, err := resolveEnv(cmd)
+			if err != nil {
+				return err
+			}
+			buildDir := envBuildDir(cmd, env)
 
-			// Load values from values.json
-			values, err := utils.LoadValuesFromFile("values.json")
+			overrides, err := loadValueOverrides(cmd)
+			if err != nil {
+				return err
+			}
+			sources, err := loadLinkValues(env)
+			if err != nil {
+				return err
+			}
+			values, err := mergeValueSources(sources, utils.MergeOptions{})
 			if err != nil {
-				return fmt.Errorf("error loading values.json: %w", err)
+				return err
+			}
+			values = applyValueOverrides(os.Stderr, values, sources, overrides)
+
+			// BaseDir may reference other values, e.g. "${Home}/projects"; the rest
+			// are not needed and may reference values only the template command loads
+			baseDir, ok, err := utils.ResolveValue(values, "BaseDir")
+			if err != nil {
+				return fmt.Errorf("error resolving BaseDir: %w", err)
+			}
+			if ok {
+				values["BaseDir"] = baseDir
 			}
 
 			// Create symlinks
@@ -35,10 +57,42 @@ func NewLinkCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringP("build-dir", "b", "build", "Build directory to create symlinks from")
+	cmd.Flags().StringP("build-dir", "b", "build", "Build directory to create symlinks from; build-<env> when --env is set")
+	addEnvFlag(cmd)
+	addValueOverrideFlags(cmd)
 	return cmd
 }
 
+// loadLinkValues loads values.json (or values.yaml / values.toml) and, with a
+// profile, its values file to layer over it, so each machine can use its own BaseDir
+func loadLinkValues(env string) ([]valueSource, error) {
+	valuesFile, err := utils.FindValuesFile("values")
+	if err != nil {
+		return nil, err
+	}
+	values, err := utils.LoadValuesFromFile(valuesFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", valuesFile, err)
+	}
+	sources := []valueSource{{Name: valuesFile, Values: values}}
+	if env == "" {
+		return sources, nil
+	}
+
+	envFile, err := utils.FindValuesFile("values." + env)
+	if err != nil {
+		return nil, err
+	}
+	if !fileExists(envFile) {
+		return sources, nil
+	}
+	envValues, err := utils.LoadValuesFromFile(envFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", envFile, err)
+	}
+	return append(sources, valueSource{Name: envFile, Values: envValues}), nil
+}
+
 // createSymlinks walks through the build directory and creates symlinks to BaseDir
 func createSymlinks(buildDir string, values map[string]interface{}) error {
 	// Get BaseDir from values
@@ -52,6 +106,20 @@ func createSymlinks(buildDir string, values map[string]interface{}) error {
 		return fmt.Errorf("build directory does not exist: %s", buildDir)
 	}
 
+	// Prefer the build manifest, which lists exactly the generated files
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		return err
+	}
+	if manifest != nil {
+		for _, entry := range manifest.Files {
+			if err := linkFile(buildDir, baseDir, filepath.FromSlash(entry.Path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	// Walk through the build directory
 	return filepath.WalkDir(buildDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -69,34 +137,47 @@ func createSymlinks(buildDir string, values map[string]interface{}) error {
 			return fmt.Errorf("failed to get relative path: %w", err)
 		}
 
-		// Construct target path in BaseDir
-		targetPath := filepath.Join(baseDir, relPath)
-
-		// Create parent directories in target if needed
-		targetDir := filepath.Dir(targetPath)
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			return fmt.Errorf("failed to create target directory %s: %w", targetDir, err)
+		// The manifest describes the build and is not linked into BaseDir
+		if relPath == manifestFileName {
+			return nil
 		}
 
-		// Remove existing symlink or file if it exists
-		if _, err := os.Lstat(targetPath); err == nil {
-			if err := os.Remove(targetPath); err != nil {
-				return fmt.Errorf("failed to remove existing file %s: %w", targetPath, err)
-			}
-		}
+		return linkFile(buildDir, baseDir, relPath)
+	})
+}
 
-		// Get absolute path of source file
-		absSourcePath, err := filepath.Abs(path)
-		if err != nil {
-			return fmt.Errorf("failed to get absolute path of %s: %w", path, err)
-		}
+// linkFile creates a symlink in baseDir pointing at the file relPath in buildDir,
+// replacing any existing file or symlink
+func linkFile(buildDir, baseDir, relPath string) error {
+	path := filepath.Join(buildDir, relPath)
 
-		// Create symlink
-		if err := os.Symlink(absSourcePath, targetPath); err != nil {
-			return fmt.Errorf("failed to create symlink from %s to %s: %w", absSourcePath, targetPath, err)
+	// Construct target path in BaseDir
+	targetPath := filepath.Join(baseDir, relPath)
+
+	// Create parent directories in target if needed
+	targetDir := filepath.Dir(targetPath)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return fmt.Errorf("failed to create target directory %s: %w", targetDir, err)
+	}
+
+	// Remove existing symlink or file if it exists
+	if _, err := os.Lstat(targetPath); err == nil {
+		if err := os.Remove(targetPath); err != nil {
+			return fmt.Errorf("failed to remove existing file %s: %w", targetPath, err)
 		}
+	}
 
-		fmt.Printf("Created symlink: %s -> %s\n", targetPath, absSourcePath)
-		return nil
-	})
+	// Get absolute path of source file
+	absSourcePath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of %s: %w", path, err)
+	}
+
+	// Create symlink
+	if err := os.Symlink(absSourcePath, targetPath); err != nil {
+		return fmt.Errorf("failed to create symlink from %s to %s: %w", absSourcePath, targetPath, err)
+	}
+
+	fmt.Printf("Created symlink: %s -> %s\n", targetPath, absSourcePath)
+	return nil
 }
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// manifestFileName is the file in the build directory recording where every output came from
+const manifestFileName = ".vibeops-manifest.json"
+
+// buildManifest records the provenance of every generated file in a build directory
+type buildManifest struct {
+	Files []manifestEntry `json:"files"`
+}
+
+// manifestEntry describes a single generated file
+type manifestEntry struct {
+	// Path is the output path relative to the build directory
+	Path string `json:"path"`
+	// Layer is the source directory the template was read from, e.g. "source" or "source-private"
+	Layer string `json:"layer"`
+	// Source is the template path relative to its layer
+	Source    string   `json:"source"`
+	SHA256    string   `json:"sha256"`
+	Mode      string   `json:"mode"`
+	Sensitive bool     `json:"sensitive,omitempty"`
+	ValueKeys []string `json:"valueKeys"`
+	// SecretVersions are the versions of every secret that supplied a value the template uses
+	SecretVersions []string `json:"secretVersions,omitempty"`
+}
+
+// secretProvenance identifies the secrets some of the template values were loaded from
+type secretProvenance struct {
+	// Keys maps each key loaded from a secret to the secret version that supplied it
+	Keys map[string]string
+}
+
+// loadManifest reads the manifest from buildDir. A missing manifest is not an
+// error; it returns nil so callers can fall back to walking the directory.
+func loadManifest(buildDir string) (*buildManifest, error) {
+	path := filepath.Join(buildDir, manifestFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest '%s': %w", path, err)
+	}
+
+	var manifest buildManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, utils.FormatJSONError(path, err)
+	}
+	return &manifest, nil
+}
+
+// newManifestEntry builds the manifest entry for a generated file
+func newManifestEntry(out outputFile, secret *secretProvenance) manifestEntry {
+	source, err := filepath.Rel(out.Layer, out.Source)
+	if err != nil {
+		source = out.Source
+	}
+
+	entry := manifestEntry{
+		Path:      filepath.ToSlash(out.RelPath),
+		Layer:     filepath.ToSlash(filepath.Clean(out.Layer)),
+		Source:    filepath.ToSlash(source),
+		SHA256:    out.Hash,
+		Mode:      fmt.Sprintf("%04o", out.Mode.Perm()),
+		Sensitive: out.Sensitive,
+		ValueKeys: out.ValueKeys,
+	}
+	if entry.ValueKeys == nil {
+		entry.ValueKeys = []string{}
+	}
+
+	if secret != nil {
+		versions := make(map[string]bool)
+		for _, key := range out.ValueKeys {
+			if version, ok := secret.Keys[key]; ok && !versions[version] {
+				versions[version] = true
+				entry.SecretVersions = append(entry.SecretVersions, version)
+			}
+		}
+		sort.Strings(entry.SecretVersions)
+	}
+	return entry
+}
+
+// updateManifest replaces the entries for the rendered layers in the manifest in
+// dir with the outputs of the current run. Entries written by other layers are
+// kept unless the current run generated or pruned the same path.
+func updateManifest(dir string, layers []string, result *buildResult, secret *secretProvenance) error {
+	existing, err := loadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	rendered := layerSet(layers)
+	generated := make(map[string]bool, len(result.Outputs))
+	manifest := buildManifest{Files: []manifestEntry{}}
+	for _, out := range result.Outputs {
+		entry := newManifestEntry(out, secret)
+		generated[entry.Path] = true
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	pruned := make(map[string]bool, len(result.Pruned))
+	for _, relPath := range result.Pruned {
+		pruned[relPath] = true
+	}
+	if existing != nil {
+		for _, entry := range existing.Files {
+			if !rendered[path.Clean(entry.Layer)] && !generated[entry.Path] && !pruned[entry.Path] {
+				manifest.Files = append(manifest.Files, entry)
+			}
+		}
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if _, err := writeOutputFile(filepath.Join(dir, manifestFileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// layerSet returns the cleaned, slash-separated source layers as a set, as
+// recorded in the manifest, so "./source" and "source" are the same layer
+func layerSet(layers []string) map[string]bool {
+	set := make(map[string]bool, len(layers))
+	for _, layer := range layers {
+		set[filepath.ToSlash(filepath.Clean(layer))] = true
+	}
+	return set
+}
+
+// serviceFromPath returns the service directory of a build-relative path,
+// e.g. "its-the-vibe/Poppit/.env" -> "Poppit". Paths outside a service directory return "".
+func serviceFromPath(relPath string) string {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+// changedServicesFromManifests returns the services owning files present in both
+// manifests with different content, mirroring the "Files ... differ" lines of diff -qr
+func changedServicesFromManifests(prev, cur *buildManifest) []string {
+	prevHashes := make(map[string]string, len(prev.Files))
+	for _, entry := range prev.Files {
+		prevHashes[entry.Path] = entry.SHA256
+	}
+
+	serviceMap := make(map[string]bool)
+	for _, entry := range cur.Files {
+		prevHash, ok := prevHashes[entry.Path]
+		if !ok || prevHash == entry.SHA256 {
+			continue
+		}
+		if service := serviceFromPath(entry.Path); service != "" {
+			serviceMap[service] = true
+		}
+	}
+
+	services := make([]string, 0, len(serviceMap))
+	for service := range serviceMap {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessTemplates_WritesManifest(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"__.OrgName__/Poppit/.env.tmpl":        "PASSWORD={{.RedisPassword}}\nLIST={{.PoppitListName}}",
+		"__.OrgName__/Poppit/config.yaml.tmpl": "org: {{.OrgName}}",
+	})
+
+	values := map[string]interface{}{"OrgName": "org", "RedisPassword": "secret", "PoppitListName": "list"}
+	secret := &secretProvenance{Keys: map[string]string{"RedisPassword": "projects/p/secrets/s/versions/3"}}
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{Secret: secret}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest == nil || len(manifest.Files) != 2 {
+		t.Fatalf("expected manifest with 2 files, got %+v", manifest)
+	}
+
+	env := manifest.Files[0]
+	if env.Path != "org/Poppit/.env" || env.Source != "__.OrgName__/Poppit/.env.tmpl" || env.Layer != filepath.ToSlash(sourceDir) {
+		t.Errorf("unexpected paths in manifest entry: %+v", env)
+	}
+	if env.Mode != "0400" {
+		t.Errorf("expected mode 0400, got %s", env.Mode)
+	}
+	if !reflect.DeepEqual(env.ValueKeys, []string{"PoppitListName", "RedisPassword"}) {
+		t.Errorf("unexpected value keys: %v", env.ValueKeys)
+	}
+	if !reflect.DeepEqual(env.SecretVersions, []string{secret.Keys["RedisPassword"]}) {
+		t.Errorf("expected secret version %q, got %v", secret.Keys["RedisPassword"], env.SecretVersions)
+	}
+	data, err := os.ReadFile(filepath.Join(buildDir, "org", "Poppit", ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.SHA256 != contentHash(data) {
+		t.Errorf("manifest hash does not match file content")
+	}
+
+	config := manifest.Files[1]
+	if config.Mode != "0644" || config.SecretVersions != nil {
+		t.Errorf("unexpected manifest entry for config.yaml: %+v", config)
+	}
+}
+
+func TestProcessTemplates_ManifestKeepsOtherLayers(t *testing.T) {
+	dir := t.TempDir()
+	publicDir := filepath.Join(dir, "source")
+	privateDir := filepath.Join(dir, "source-private")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, publicDir, map[string]string{"svc/public.tmpl": "public"})
+	writeTestFiles(t, privateDir, map[string]string{"svc/private.tmpl": "private"})
+
+	// The same layer given in another form, e.g. ./source-private, is still the same layer
+	for _, layer := range []string{publicDir, privateDir, privateDir + string(filepath.Separator) + "."} {
+		if _, err := processTemplates([]string{layer}, buildDir, map[string]interface{}{}, templateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("expected entries from both layers, got %+v", manifest.Files)
+	}
+	if manifest.Files[0].Layer != filepath.ToSlash(privateDir) || manifest.Files[1].Layer != filepath.ToSlash(publicDir) {
+		t.Errorf("unexpected layers: %+v", manifest.Files)
+	}
+}
+
+func TestNewManifestEntry_SecretVersions(t *testing.T) {
+	secret := &secretProvenance{Keys: map[string]string{
+		"RedisPassword": "projects/p/secrets/s/versions/3",
+		"SlackBotToken": "secrets.json.enc@sha256:ab12",
+		"ApiToken":      "projects/p/secrets/s/versions/3",
+	}}
+	out := outputFile{Layer: "./source", Source: "source/svc/.env.tmpl", RelPath: "svc/.env", ValueKeys: []string{"ApiToken", "OrgName", "RedisPassword", "SlackBotToken"}}
+
+	entry := newManifestEntry(out, secret)
+	if entry.Layer != "source" || entry.Source != "svc/.env.tmpl" {
+		t.Errorf("unexpected paths in manifest entry: %+v", entry)
+	}
+	expected := []string{"projects/p/secrets/s/versions/3", "secrets.json.enc@sha256:ab12"}
+	if !reflect.DeepEqual(entry.SecretVersions, expected) {
+		t.Errorf("SecretVersions = %v, want %v", entry.SecretVersions, expected)
+	}
+}
+
+func TestChangedServicesFromManifests(t *testing.T) {
+	prev := &buildManifest{Files: []manifestEntry{
+		{Path: "org/Poppit/.env", SHA256: "a"},
+		{Path: "org/Poppit/.env-builder", SHA256: "b"},
+		{Path: "org/WatchPot/config.yaml", SHA256: "c"},
+		{Path: "org/Removed/.env", SHA256: "d"},
+	}}
+	cur := &buildManifest{Files: []manifestEntry{
+		{Path: "org/Poppit/.env", SHA256: "changed"},
+		{Path: "org/Poppit/.env-builder", SHA256: "changed"},
+		{Path: "org/WatchPot/config.yaml", SHA256: "c"},
+		{Path: "org/Added/.env", SHA256: "e"},
+	}}
+
+	services := changedServicesFromManifests(prev, cur)
+	if !reflect.DeepEqual(services, []string{"Poppit"}) {
+		t.Errorf("expected [Poppit], got %v", services)
+	}
+}
+
+func TestCreateSymlinks_UsesManifest(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+	baseDir := filepath.Join(dir, "base")
+
+	writeTestFiles(t, sourceDir, map[string]string{"svc/config.tmpl": "x"})
+	if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// A stray file not produced by any template is not linked
+	writeTestFiles(t, buildDir, map[string]string{"svc/stray": "y"})
+
+	if err := createSymlinks(buildDir, map[string]interface{}{"BaseDir": baseDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if target, err := os.Readlink(filepath.Join(baseDir, "svc", "config")); err != nil || target != filepath.Join(buildDir, "svc", "config") {
+		t.Errorf("expected symlink to generated file, got %q (err %v)", target, err)
+	}
+	for _, name := range []string{manifestFileName, filepath.Join("svc", "stray")} {
+		if _, err := os.Lstat(filepath.Join(baseDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be linked, got err=%v", name, err)
+		}
+	}
+}
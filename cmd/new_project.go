@@ -24,6 +24,15 @@ will be automatically generated from projects.json when you run 'vibeops templat
 		RunE: func(cmd *cobra.Command, args []string) error {
 			projectName := args[0]
 
+			// Only projects.json is rewritten; YAML and TOML files keep their comments by being edited by hand
+			projectsFile, err := utils.FindValuesFile("projects")
+			if err != nil {
+				return err
+			}
+			if projectsFile != "projects.json" {
+				return fmt.Errorf("new-project only updates projects.json; add '%s' to %s by hand", projectName, projectsFile)
+			}
+
 			// Add project to root projects.json
 			fmt.Printf("Adding project '%s' to projects.json...\n", projectName)
 			if err := utils.AddProjectToProjectsFile("projects.json", projectName); err != nil {
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// outputStatus describes what happened to a generated file during a run
+type outputStatus int
+
+const (
+	outputCreated outputStatus = iota
+	outputUpdated
+	outputUnchanged
+)
+
+func (s outputStatus) String() string {
+	switch s {
+	case outputCreated:
+		return "created"
+	case outputUpdated:
+		return "updated"
+	case outputUnchanged:
+		return "unchanged"
+	default:
+		return "unknown"
+	}
+}
+
+// outputFile records a single file generated from a template
+type outputFile struct {
+	// Path is where the file was written and RelPath is relative to the build directory
+	Path    string
+	RelPath string
+	Source  string
+	// Layer is the source directory the template was read from
+	Layer  string
+	Status outputStatus
+	Hash   string
+	Mode   os.FileMode
+	// Sensitive reports whether the output holds secrets, by suffix or front matter
+	Sensitive bool
+	// ValueKeys are the top-level value keys the template references
+	ValueKeys []string
+	// Warnings are printed once rendering is complete, in template order
+	Warnings []string
+}
+
+// buildResult describes every output produced by a processTemplates run
+type buildResult struct {
+	Outputs []outputFile
+	// Pruned lists the build-relative paths of stale files removed by --prune
+	Pruned []string
+}
+
+// Add records a generated file
+func (r *buildResult) Add(out outputFile) {
+	r.Outputs = append(r.Outputs, out)
+}
+
+// Count returns the number of outputs with the given status
+func (r *buildResult) Count(status outputStatus) int {
+	n := 0
+	for _, out := range r.Outputs {
+		if out.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary returns a one-line description of the run, e.g. "2 created, 1 updated, 20 unchanged, 1 pruned"
+func (r *buildResult) Summary() string {
+	summary := fmt.Sprintf("%d created, %d updated, %d unchanged",
+		r.Count(outputCreated), r.Count(outputUpdated), r.Count(outputUnchanged))
+	if len(r.Pruned) > 0 {
+		summary += fmt.Sprintf(", %d pruned", len(r.Pruned))
+	}
+	return summary
+}
+
+// fileOwner is the ownership applied to an output; -1 leaves the uid or gid unchanged
+type fileOwner struct {
+	UID int
+	GID int
+}
+
+// noOwner leaves the ownership of an output as the process creates it
+var noOwner = fileOwner{UID: -1, GID: -1}
+
+// matches reports whether a file has the requested ownership. Platforms that do
+// not report ownership always match.
+func (o fileOwner) matches(info os.FileInfo) bool {
+	if o == noOwner {
+		return true
+	}
+	actual, ok := fileOwnerOf(info)
+	if !ok {
+		return true
+	}
+	return (o.UID == -1 || o.UID == actual.UID) && (o.GID == -1 || o.GID == actual.GID)
+}
+
+// contentHash returns the hex-encoded SHA-256 of data
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeOutputFile writes content to path with the given mode, leaving ownership
+// as the process creates it. See writeOwnedOutputFile.
+func writeOutputFile(path string, content []byte, mode os.FileMode) (outputStatus, error) {
+	return writeOwnedOutputFile(path, content, mode, noOwner)
+}
+
+// writeOwnedOutputFile writes content to path with the given mode and owner, but
+// only when the existing file differs. Unchanged files are not touched, so their mtimes are
+// preserved. Changed files are written to a temporary file in the same directory
+// and renamed into place, which also replaces read-only (e.g. 0400) outputs safely.
+// Existing files are never modified in place, so a file hard-linked from the
+// live build into a staging directory is never changed underneath it.
+func writeOwnedOutputFile(path string, content []byte, mode os.FileMode, owner fileOwner) (outputStatus, error) {
+	status := outputCreated
+	if info, err := os.Stat(path); err == nil {
+		if !info.Mode().IsRegular() {
+			return 0, fmt.Errorf("output path %s exists and is not a regular file", path)
+		}
+		existing, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read existing output file: %w", err)
+		}
+		if contentHash(existing) == contentHash(content) && info.Mode().Perm() == mode.Perm() && owner.matches(info) {
+			return outputUnchanged, nil
+		}
+		status = outputUpdated
+	} else if !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to stat output file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".vibeops-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary output file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to write output file: %w", err)
+	}
+	if owner != noOwner {
+		if err := os.Chown(tmpPath, owner.UID, owner.GID); err != nil {
+			os.Remove(tmpPath)
+			return 0, fmt.Errorf("failed to set ownership of output file: %w", err)
+		}
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to set permissions on output file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to replace output file: %w", err)
+	}
+
+	return status, nil
+}
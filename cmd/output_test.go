@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteOutputFile_CreatedUnchangedUpdated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	status, err := writeOutputFile(path, []byte(`{"a":1}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != outputCreated {
+		t.Errorf("expected created, got %s", status)
+	}
+
+	// Backdate the file so an unwanted rewrite would be visible in the mtime
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err = writeOutputFile(path, []byte(`{"a":1}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != outputUnchanged {
+		t.Errorf("expected unchanged, got %s", status)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("expected mtime to be preserved, got %v want %v", info.ModTime(), past)
+	}
+
+	status, err = writeOutputFile(path, []byte(`{"a":2}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != outputUpdated {
+		t.Errorf("expected updated, got %s", status)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"a":2}` {
+		t.Errorf("expected updated content, got %q", string(data))
+	}
+}
+
+func TestWriteOutputFile_ReplacesReadOnlyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+
+	if _, err := writeOutputFile(path, []byte("SECRET=old"), 0400); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status, err := writeOutputFile(path, []byte("SECRET=new"), 0400)
+	if err != nil {
+		t.Fatalf("unexpected error replacing read-only file: %v", err)
+	}
+	if status != outputUpdated {
+		t.Errorf("expected updated, got %s", status)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0400 {
+		t.Errorf("expected permissions 0400, got %o", info.Mode().Perm())
+	}
+
+	// No temporary files should be left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the output file in %s, got %d entries", dir, len(entries))
+	}
+}
+
+func TestWriteOutputFile_ModeChangeOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.secret")
+
+	if _, err := writeOutputFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, err := writeOutputFile(path, []byte("x"), 0400)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != outputUpdated {
+		t.Errorf("expected updated for mode change, got %s", status)
+	}
+}
+
+func TestProcessTemplates_Incremental(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"svc/.env.tmpl":        "KEY={{.Key}}",
+		"svc/config.yaml.tmpl": "other: {{.Other}}",
+	})
+
+	values := map[string]interface{}{"Key": "one", "Other": "x"}
+	result, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Count(outputCreated) != 2 {
+		t.Errorf("expected 2 created on first run, got %s", result.Summary())
+	}
+
+	// A second run over existing 0400 .env files must succeed and change one file
+	values["Key"] = "two"
+	result, err = processTemplates([]string{sourceDir}, buildDir, values, templateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error on second run: %v", err)
+	}
+	if result.Summary() != "0 created, 1 updated, 1 unchanged" {
+		t.Errorf("unexpected summary on second run: %s", result.Summary())
+	}
+
+	data, err := os.ReadFile(filepath.Join(buildDir, "svc", ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "KEY=two" {
+		t.Errorf("expected 'KEY=two', got %q", string(data))
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// valueEnvPrefix is the prefix of environment variables that override template
+// values, e.g. VIBEOPS_VALUE_OrgName
+const valueEnvPrefix = "VIBEOPS_VALUE_"
+
+// addValueOverrideFlags adds the flags overriding template values for one run
+func addValueOverrideFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("values-file", nil, "Extra values file (JSON, YAML or TOML) merged over the loaded values (repeatable)")
+	cmd.Flags().StringArray("set-json", nil, "Override a value with a JSON value (Key='{...}', repeatable)")
+	cmd.Flags().StringArray("set", nil, "Override a value (Key=Value, repeatable)")
+}
+
+// loadValueOverrides loads the layers overriding the loaded values, lowest
+// precedence first: each --values-file in order, --set-json, --set and finally
+// the VIBEOPS_VALUE_<Key> environment variables. Only the values files may
+// contain ${Key} references; the other overrides are used as they are.
+func loadValueOverrides(cmd *cobra.Command) ([]valueSource, error) {
+	valuesFiles, _ := cmd.Flags().GetStringArray("values-file")
+	setJSON, _ := cmd.Flags().GetStringArray("set-json")
+	sets, _ := cmd.Flags().GetStringArray("set")
+
+	var overrides []valueSource
+	for _, file := range valuesFiles {
+		// Unlike the default values files, a file given explicitly must exist
+		if !fileExists(file) {
+			return nil, fmt.Errorf("values file '%s' not found", file)
+		}
+		values, err := utils.LoadValuesFromFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s: %w", file, err)
+		}
+		overrides = append(overrides, valueSource{Name: "--values-file " + file, Values: values})
+	}
+
+	jsonValues, err := parseSetJSONValues(setJSON)
+	if err != nil {
+		return nil, err
+	}
+	overrides = append(overrides, valueSource{Name: "--set-json", Values: jsonValues, Literal: true})
+
+	setValues, err := parseSetValues(sets)
+	if err != nil {
+		return nil, err
+	}
+	overrides = append(overrides, valueSource{Name: "--set", Values: setValues, Literal: true})
+
+	// Name each variable as its own layer so the report shows exactly which one applied
+	envValues := envValueOverrides(os.Environ())
+	envKeys := make([]string, 0, len(envValues))
+	for key := range envValues {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		overrides = append(overrides, valueSource{Name: valueEnvPrefix + key, Values: map[string]interface{}{key: envValues[key]}, Literal: true})
+	}
+	return overrides, nil
+}
+
+// parseSetJSONValues parses Key=JSON overrides, as given to --set-json
+func parseSetJSONValues(pairs []string) (map[string]interface{}, error) {
+	overrides := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		key, raw, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set-json value %q: expected Key=JSON", pair)
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, fmt.Errorf("invalid --set-json value for %s: %w", key, err)
+		}
+		overrides[key] = value
+	}
+	return overrides, nil
+}
+
+// envValueOverrides returns the values set by VIBEOPS_VALUE_<Key> variables in
+// environ, a list of NAME=value entries as returned by os.Environ
+func envValueOverrides(environ []string) map[string]interface{} {
+	overrides := make(map[string]interface{})
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, valueEnvPrefix) {
+			continue
+		}
+		if key := strings.TrimPrefix(name, valueEnvPrefix); key != "" {
+			overrides[key] = value
+		}
+	}
+	return overrides
+}
+
+// applyValueOverrides merges each override layer over values with MergeValues, so
+// an override replaces a top-level key wholesale, and writes to w which layer
+// supplied each overridden key and which source it replaced. sources are the
+// layers values was merged from.
+func applyValueOverrides(w io.Writer, values map[string]interface{}, sources, overrides []valueSource) map[string]interface{} {
+	// origins tracks the layer that supplied each top-level key so far
+	origins := make(map[string]string)
+	for _, source := range sources {
+		for key := range source.Values {
+			origins[key] = source.Name
+		}
+	}
+
+	type override struct {
+		layer, replaced string
+	}
+	applied := make(map[string]override)
+	for _, layer := range overrides {
+		for key := range layer.Values {
+			applied[key] = override{layer: layer.Name, replaced: origins[key]}
+			origins[key] = layer.Name
+		}
+		values = utils.MergeValues(values, layer.Values)
+	}
+
+	keys := make([]string, 0, len(applied))
+	for key := range applied {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if replaced := applied[key].replaced; replaced != "" {
+			fmt.Fprintf(w, "ℹ %s supplied by %s (overriding %s)\n", key, applied[key].layer, replaced)
+		} else {
+			fmt.Fprintf(w, "ℹ %s supplied by %s\n", key, applied[key].layer)
+		}
+	}
+	return values
+}
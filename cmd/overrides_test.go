@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func TestParseSetJSONValues(t *testing.T) {
+	values, err := parseSetJSONValues([]string{`Redis={"Host": "redis", "Port": 6379}`, `Tags=["a", "b"]`, `Enabled=true`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"Redis":   map[string]interface{}{"Host": "redis", "Port": float64(6379)},
+		"Tags":    []interface{}{"a", "b"},
+		"Enabled": true,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("parseSetJSONValues() = %v, want %v", values, expected)
+	}
+
+	for _, pair := range []string{"Redis", "=1", "Redis={"} {
+		if _, err := parseSetJSONValues([]string{pair}); err == nil {
+			t.Errorf("expected an error for %q", pair)
+		}
+	}
+}
+
+func TestEnvValueOverrides(t *testing.T) {
+	environ := []string{"HOME=/root", "VIBEOPS_VALUE_OrgName=acme", "VIBEOPS_VALUE_=x", "VIBEOPS_VALUE_Url=a=b", "VIBEOPS_ENV=prod"}
+	expected := map[string]interface{}{"OrgName": "acme", "Url": "a=b"}
+	if got := envValueOverrides(environ); !reflect.DeepEqual(got, expected) {
+		t.Errorf("envValueOverrides() = %v, want %v", got, expected)
+	}
+}
+
+func TestLoadValueOverrides_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"extra.yaml": "OrgName: from-file\nBaseDir: /srv\nRegion: eu\n",
+	})
+	t.Setenv("VIBEOPS_VALUE_Region", "us")
+
+	cmd := &cobra.Command{}
+	addValueOverrideFlags(cmd)
+	if err := cmd.ParseFlags([]string{"--values-file", filepath.Join(dir, "extra.yaml"), "--set", "OrgName=from-set", "--set-json", `OrgName="from-json"`, "--set-json", `Redis={"Host": "redis"}`}); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := loadValueOverrides(cmd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sources := []valueSource{
+		{Name: "values.json", Values: map[string]interface{}{"OrgName": "base", "Redis": map[string]interface{}{"Host": "localhost", "Port": "6379"}, "Other": "kept"}},
+	}
+	merged, err := mergeValueSources(sources, utils.MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	values := applyValueOverrides(&out, merged, sources, overrides)
+
+	expected := map[string]interface{}{
+		"OrgName": "from-set",
+		"BaseDir": "/srv",
+		"Region":  "us",
+		"Redis":   map[string]interface{}{"Host": "redis"},
+		"Other":   "kept",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("values = %v, want %v", values, expected)
+	}
+
+	expectedOut := "ℹ BaseDir supplied by --values-file " + filepath.Join(dir, "extra.yaml") + "\n" +
+		"ℹ OrgName supplied by --set (overriding --set-json)\n" +
+		"ℹ Redis supplied by --set-json (overriding values.json)\n" +
+		"ℹ Region supplied by VIBEOPS_VALUE_Region (overriding --values-file " + filepath.Join(dir, "extra.yaml") + ")\n"
+	if out.String() != expectedOut {
+		t.Errorf("report =\n%s\nwant\n%s", out.String(), expectedOut)
+	}
+}
+
+func TestLoadValueOverrides_MissingValuesFile(t *testing.T) {
+	cmd := &cobra.Command{}
+	addValueOverrideFlags(cmd)
+	if err := cmd.ParseFlags([]string{"--values-file", filepath.Join(t.TempDir(), "missing.json")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadValueOverrides(cmd); err == nil {
+		t.Error("expected an error for a missing --values-file")
+	}
+}
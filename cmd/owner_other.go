@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// fileOwnerOf returns the uid and gid of a file, if the platform reports them
+func fileOwnerOf(info os.FileInfo) (fileOwner, bool) {
+	return noOwner, false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwnerOf returns the uid and gid of a file, if the platform reports them
+func fileOwnerOf(info os.FileInfo) (fileOwner, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return noOwner, false
+	}
+	return fileOwner{UID: int(stat.Uid), GID: int(stat.Gid)}, true
+}
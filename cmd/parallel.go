@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"runtime"
+	"sync"
+	"text/template"
+)
+
+// renderOutcome is the result of rendering and writing a single templateSource
+type renderOutcome struct {
+	Output outputFile
+	Err    error
+}
+
+// defaultJobs returns the number of templates rendered concurrently when --jobs is not set
+func defaultJobs() int {
+	return runtime.NumCPU()
+}
+
+// renderInParallel renders and writes every source into outputDir using a pool of
+// opts.Jobs workers. Each template file is parsed once by a single worker, which
+// then renders all of its outputs (one per project for fan-out templates). The
+// outcomes are returned in the same order as sources, whatever the scheduling.
+func renderInParallel(sources []templateSource, outputDir string, values map[string]interface{}, partials *template.Template, opts templateOptions) []renderOutcome {
+	// Group the outputs of each template file so it is only parsed once
+	var groups [][]int
+	groupOf := make(map[string]int)
+	for i, src := range sources {
+		g, ok := groupOf[src.Path]
+		if !ok {
+			g = len(groups)
+			groupOf[src.Path] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = defaultJobs()
+	}
+	if jobs > len(groups) {
+		jobs = len(groups)
+	}
+
+	outcomes := make([]renderOutcome, len(sources))
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				renderGroup(sources, group, outcomes, outputDir, values, partials, opts)
+			}
+		}()
+	}
+	for _, group := range groups {
+		work <- group
+	}
+	close(work)
+	wg.Wait()
+
+	return outcomes
+}
+
+// renderGroup parses the template shared by the sources at the given indices
+// and renders each of them, storing the outcomes at the same indices
+func renderGroup(sources []templateSource, group []int, outcomes []renderOutcome, outputDir string, values map[string]interface{}, partials *template.Template, opts templateOptions) {
+	first := sources[group[0]]
+	parsed, err := parseTemplate(first.Path, first.Content, partials)
+	if err != nil {
+		for _, i := range group {
+			outcomes[i].Err = err
+		}
+		return
+	}
+
+	for _, i := range group {
+		src := sources[i]
+		templateValues := values
+		if src.Project != nil {
+			templateValues = withProject(values, src.Project)
+		}
+		if src.ServiceDir != "" {
+			templateValues = withService(templateValues, src.ServiceDir)
+		}
+
+		rendered, err := parsed.execute(templateValues, opts)
+		if err != nil {
+			outcomes[i].Err = err
+			continue
+		}
+		out, err := writeRenderedOutput(rendered, src.Path, outputDir, src.OutputRelPath, opts)
+		if err != nil {
+			outcomes[i].Err = err
+			continue
+		}
+
+		out.Layer = src.Layer
+		if src.Project != nil {
+			out.ValueKeys = fanOutValueKeys(out.ValueKeys)
+		}
+		if src.ServiceDir != "" {
+			out.ValueKeys = serviceValueKeys(out.ValueKeys)
+		}
+		outcomes[i].Output = out
+	}
+}
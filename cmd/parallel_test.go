@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcessTemplates_ParallelCollectsEveryError(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+
+	files := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("svc%02d/config.json.tmpl", i)] = fmt.Sprintf(`{"index": %d}`, i)
+	}
+	files["svc05/broken.json.tmpl"] = `{"broken": }`
+	files["svc15/broken.tmpl"] = `{{ required "Missing is required" .Missing }}`
+	files["svc10/unparsable.tmpl"] = `{{ if }}`
+	writeTestFiles(t, sourceDir, files)
+
+	var messages []string
+	for _, jobs := range []int{1, 8} {
+		buildDir := filepath.Join(dir, fmt.Sprintf("build-%d", jobs))
+		_, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{Jobs: jobs})
+		if err == nil {
+			t.Fatalf("jobs=%d: expected error", jobs)
+		}
+		messages = append(messages, strings.ReplaceAll(err.Error(), buildDir, "build"))
+	}
+
+	if messages[0] != messages[1] {
+		t.Errorf("expected the same errors for any number of jobs, got:\n%s\n---\n%s", messages[0], messages[1])
+	}
+	for _, want := range []string{"svc05/broken.json.tmpl", "svc10/unparsable.tmpl", "svc15/broken.tmpl"} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("expected error for %s, got:\n%s", want, messages[0])
+		}
+	}
+	if first, last := strings.Index(messages[0], "svc05"), strings.Index(messages[0], "svc15"); first > last {
+		t.Errorf("expected errors in discovery order, got:\n%s", messages[0])
+	}
+}
+
+func TestProcessTemplates_ParallelMatchesSequential(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	writeSyntheticTree(t, sourceDir, 200)
+
+	values := syntheticValues(10)
+	var manifests []*buildManifest
+	for _, jobs := range []int{1, 8} {
+		buildDir := filepath.Join(dir, fmt.Sprintf("build-%d", jobs))
+		result, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{Jobs: jobs, Strict: true})
+		if err != nil {
+			t.Fatalf("jobs=%d: unexpected error: %v", jobs, err)
+		}
+		if got := result.Count(outputCreated); got != 210 {
+			t.Errorf("jobs=%d: expected 210 outputs, got %d", jobs, got)
+		}
+		manifest, err := loadManifest(buildDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	if !reflect.DeepEqual(manifests[0], manifests[1]) {
+		t.Error("expected parallel and sequential builds to produce the same manifest")
+	}
+}
+
+// writeSyntheticTree writes n service templates plus a per-project fan-out
+// template sharing a partial, resembling a large source tree
+func writeSyntheticTree(tb testing.TB, root string, n int) {
+	tb.Helper()
+	files := map[string]string{
+		"_partials/redis.tmpl": `{{ define "redis" }}redis:
+  addr: "{{ .RedisHost }}:6379"
+  password: {{ .RedisPassword | quote }}{{ end }}`,
+		"__.OrgName__/__each.Projects__/deploy.json.tmpl": `{"project": {{ toJson .Project.name }}, "org": {{ toJson .OrgName }}}`,
+	}
+	for i := 0; i < n; i++ {
+		files[fmt.Sprintf("__.OrgName__/Service%04d/config.yaml.tmpl", i)] = fmt.Sprintf(`service: Service%04d
+{{ template "redis" . }}
+channels:
+{{- range $i, $p := filterProjects .Projects "enabled" }}
+  - {{ $p.name | lower }}-events
+{{- end }}
+`, i)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func syntheticValues(projects int) map[string]interface{} {
+	list := make([]map[string]interface{}, projects)
+	for i := range list {
+		list[i] = map[string]interface{}{"name": fmt.Sprintf("Project%02d", i), "enabled": i%2 == 0}
+	}
+	return map[string]interface{}{
+		"OrgName":       "org",
+		"RedisHost":     "localhost",
+		"RedisPassword": "password",
+		"Projects":      list,
+	}
+}
+
+// BenchmarkProcessTemplates renders a synthetic tree of a few thousand templates
+// with an increasing number of workers, e.g.
+//
+//	go test ./cmd -run '^$' -bench ProcessTemplates -benchtime 5x
+func BenchmarkProcessTemplates(b *testing.B) {
+	sourceDir := filepath.Join(b.TempDir(), "source")
+	writeSyntheticTree(b, sourceDir, 3000)
+	values := syntheticValues(20)
+
+	for _, jobs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// Render into a fresh directory each time so every output is written
+				buildDir := filepath.Join(b.TempDir(), "build")
+				if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{Jobs: jobs, Quiet: true}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	// partialsDirName is the name of directories whose templates are shared partials
+	partialsDirName = "_partials"
+	// partialSuffix marks an individual template file as a shared partial
+	partialSuffix = ".partial.tmpl"
+)
+
+// isPartialPath reports whether the path (relative to the source directory)
+// is a shared partial, i.e. lives under a _partials directory or ends in .partial.tmpl.
+// Partials are never emitted as output files.
+func isPartialPath(relPath string) bool {
+	if strings.HasSuffix(relPath, partialSuffix) {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(relPath), "/") {
+		if part == partialsDirName {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPartials parses every partial in the source layers once and returns a
+// template holding their {{define}} blocks, ready to be cloned for each template.
+// Defining the same block name in two partials, even in different layers, is an error.
+func loadPartials(sourceDirs []string, followSymlinks bool) (*template.Template, error) {
+	partials := template.New(partialsDirName).Funcs(templateFuncs())
+	definedIn := make(map[string]string)
+
+	for _, sourceDir := range sourceDirs {
+		if err := loadLayerPartials(partials, definedIn, sourceDir, followSymlinks); err != nil {
+			return nil, err
+		}
+	}
+	return partials, nil
+}
+
+// loadLayerPartials registers the partials of a single source layer, recording
+// where each block was defined in definedIn
+func loadLayerPartials(partials *template.Template, definedIn map[string]string, sourceDir string, followSymlinks bool) error {
+	walkFn := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".tmpl") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		if !isPartialPath(relPath) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read partial %s: %w", path, err)
+		}
+
+		parsed, err := template.New(path).Funcs(templateFuncs()).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse partial %s: %w", path, err)
+		}
+
+		// Register each {{define}} block, skipping the (unused) file body itself
+		names := make([]string, 0, len(parsed.Templates()))
+		for _, t := range parsed.Templates() {
+			if t.Name() != path {
+				names = append(names, t.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if previous, exists := definedIn[name]; exists {
+				return fmt.Errorf("partial %q is defined in both %s and %s", name, previous, path)
+			}
+			definedIn[name] = path
+			if _, err := partials.AddParseTree(name, parsed.Lookup(name).Tree); err != nil {
+				return fmt.Errorf("failed to register partial %q from %s: %w", name, path, err)
+			}
+		}
+		return nil
+	}
+
+	if followSymlinks {
+		return walkDirFollowSymlinks(sourceDir, walkFn)
+	}
+	return filepath.WalkDir(sourceDir, walkFn)
+}
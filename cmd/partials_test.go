@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsPartialPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"_partials/redis.tmpl", true},
+		{"__.OrgName__/_partials/redis.tmpl", true},
+		{"__.OrgName__/Poppit/env.partial.tmpl", true},
+		{"__.OrgName__/Poppit/.env.tmpl", false},
+		{"__.OrgName__/my_partials/x.tmpl", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isPartialPath(tt.path); got != tt.expected {
+				t.Errorf("isPartialPath(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProcessTemplates_Partials(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"_partials/redis.tmpl":                `{{ define "redis" }}addr: "{{ .RedisHost }}:6379"{{ end }}`,
+		"svc/channels.partial.tmpl":           `{{ define "channel" }}{{ . }}-events{{ end }}`,
+		"svc/config.yaml.tmpl":                `{{ template "redis" . }}` + "\nchannel: {{ template \"channel\" \"poppit\" }}",
+		"other/config.yaml.tmpl":              `{{ template "redis" . }}`,
+		"other/_partials/nested.partial.tmpl": `{{ define "unused" }}{{ end }}`,
+	})
+
+	values := map[string]interface{}{"RedisHost": "redis.local"}
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(buildDir, "svc", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "addr: \"redis.local:6379\"\nchannel: poppit-events" {
+		t.Errorf("unexpected output: %q", string(data))
+	}
+
+	data, err = os.ReadFile(filepath.Join(buildDir, "other", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `addr: "redis.local:6379"` {
+		t.Errorf("unexpected output: %q", string(data))
+	}
+
+	// Partials must never be emitted
+	for _, path := range []string{"_partials", "svc/channels.partial", "other/_partials"} {
+		if _, err := os.Stat(filepath.Join(buildDir, path)); !os.IsNotExist(err) {
+			t.Errorf("expected partial %s not to be emitted, got err=%v", path, err)
+		}
+	}
+}
+
+func TestLoadPartials_DuplicateDefine(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{
+		"_partials/a.tmpl":     `{{ define "redis" }}a{{ end }}`,
+		"svc/b.partial.tmpl":   `{{ define "redis" }}b{{ end }}`,
+		"svc/config.yaml.tmpl": `{{ template "redis" . }}`,
+	})
+
+	_, err := loadPartials([]string{sourceDir}, false)
+	if err == nil {
+		t.Fatal("expected error for duplicate partial definition, got nil")
+	}
+	if !strings.Contains(err.Error(), `"redis"`) {
+		t.Errorf("expected error to name the duplicate partial, got %v", err)
+	}
+}
+
+func TestLoadPartials_IgnoresRegularTemplates(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{
+		"svc/config.yaml.tmpl": `{{ define "local" }}x{{ end }}{{ template "local" }}`,
+	})
+
+	partials, err := loadPartials([]string{sourceDir}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if partials.Lookup("local") != nil {
+		t.Error("expected blocks defined in regular templates not to be shared")
+	}
+}
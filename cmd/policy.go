@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// minSecretValueLength is the shortest secret value searched for in rendered
+// outputs; shorter values would match unrelated text too often
+const minSecretValueLength = 6
+
+// outputPolicy is a loaded utils.OutputPolicy with its modes and owners resolved
+type outputPolicy struct {
+	Rules      []policyRule
+	SecretKeys []string
+}
+
+// policyRule is a resolved sensitive-output rule
+type policyRule struct {
+	Pattern string
+	Mode    os.FileMode
+	Owner   fileOwner
+}
+
+// newOutputPolicy validates a policy and resolves its modes, owners and groups
+func newOutputPolicy(policy *utils.OutputPolicy) (*outputPolicy, error) {
+	resolved := &outputPolicy{SecretKeys: policy.SecretKeys}
+	for _, rule := range policy.SensitiveOutputs {
+		r := policyRule{Pattern: rule.Pattern, Mode: 0400, Owner: noOwner}
+
+		if rule.Mode != "" {
+			mode, err := strconv.ParseUint(rule.Mode, 8, 32)
+			if err != nil || mode > 0777 {
+				return nil, fmt.Errorf("invalid mode %q for sensitive output pattern %q: expected an octal permission such as \"0400\"", rule.Mode, rule.Pattern)
+			}
+			r.Mode = os.FileMode(mode)
+		}
+		if r.Mode&0007 != 0 {
+			return nil, fmt.Errorf("invalid mode %q for sensitive output pattern %q: sensitive outputs must not be accessible to others", rule.Mode, rule.Pattern)
+		}
+
+		if rule.Owner != "" {
+			uid, err := lookupID(rule.Owner, func(name string) (string, error) {
+				u, err := user.Lookup(name)
+				if err != nil {
+					return "", err
+				}
+				return u.Uid, nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("invalid owner %q for sensitive output pattern %q: %w", rule.Owner, rule.Pattern, err)
+			}
+			r.Owner.UID = uid
+		}
+		if rule.Group != "" {
+			gid, err := lookupID(rule.Group, func(name string) (string, error) {
+				g, err := user.LookupGroup(name)
+				if err != nil {
+					return "", err
+				}
+				return g.Gid, nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("invalid group %q for sensitive output pattern %q: %w", rule.Group, rule.Pattern, err)
+			}
+			r.Owner.GID = gid
+		}
+
+		resolved.Rules = append(resolved.Rules, r)
+	}
+	return resolved, nil
+}
+
+// lookupID returns a numeric user or group id as is, or resolves a name with lookup
+func lookupID(nameOrID string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+	idStr, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(idStr)
+}
+
+// match returns the first rule matching the build-relative path, or nil
+func (p *outputPolicy) match(relPath string) *policyRule {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Rules {
+		if matchGlob(p.Rules[i].Pattern, relPath) {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// matchGlob matches a slash-separated path against a glob pattern. A pattern
+// without a slash is matched against the file name alone; otherwise each path
+// segment is matched in turn, with ** matching any number of segments.
+func matchGlob(pattern, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(relPath))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
+}
+
+// collectSecretValues returns the string values of the given keys that are
+// long enough to search for in rendered outputs, keyed by dotted value path. A
+// key may name a nested value, e.g. "Redis.Password", and every string nested
+// in a secret map or list is collected, e.g. "Redis.Password" for the key "Redis".
+func collectSecretValues(values map[string]interface{}, keys ...[]string) map[string]string {
+	secrets := make(map[string]string)
+	for _, list := range keys {
+		for _, key := range list {
+			if value, ok := lookupValuePath(values, key); ok {
+				collectSecretStrings(secrets, key, value)
+			}
+		}
+	}
+	return secrets
+}
+
+// lookupValuePath returns the value at a dotted path through nested maps
+func lookupValuePath(values map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = values
+	for _, part := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// collectSecretStrings adds the strings in value, found at path, that are long
+// enough to search for, walking nested maps and lists
+func collectSecretStrings(secrets map[string]string, path string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		if len(v) >= minSecretValueLength {
+			secrets[path] = v
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			collectSecretStrings(secrets, path+"."+key, item)
+		}
+	case []map[string]interface{}:
+		for i, item := range v {
+			collectSecretStrings(secrets, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			collectSecretStrings(secrets, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	}
+}
+
+// secretKeysIn returns the sorted keys whose secret values appear in content
+func secretKeysIn(content []byte, secrets map[string]string) []string {
+	var keys []string
+	for key, value := range secrets {
+		if bytes.Contains(content, []byte(value)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// outputPerms is the mode and ownership chosen for an output
+type outputPerms struct {
+	Mode      os.FileMode
+	Owner     fileOwner
+	Sensitive bool
+	// Warning explains why the mode was forced, if it was
+	Warning string
+}
+
+// outputPermissions decides the mode and ownership of an output. Outputs matching
+// a policy rule or marked sensitive in their front matter are sensitive. An
+// explicit front-matter mode may narrow the policy's mode but is an error if it
+// grants more access, or lets others read a sensitive output. Any other output
+// that contains a secret value is forced to 0400 with a warning, since it matched
+// no policy.
+func outputPermissions(relPath string, content []byte, fm frontMatter, opts templateOptions) (outputPerms, error) {
+	policy := opts.Policy
+	if policy == nil {
+		var err error
+		if policy, err = newOutputPolicy(utils.DefaultOutputPolicy()); err != nil {
+			return outputPerms{}, err
+		}
+	}
+
+	perms := outputPerms{Mode: 0644, Owner: noOwner, Sensitive: fm.Sensitive}
+	if fm.Sensitive {
+		perms.Mode = 0400
+	}
+	rule := policy.match(relPath)
+	if rule != nil {
+		perms.Sensitive = true
+		perms.Mode = rule.Mode
+		perms.Owner = rule.Owner
+	}
+	fmMode, hasMode, err := fm.fileMode()
+	if err != nil {
+		return outputPerms{}, err
+	}
+	if hasMode {
+		// A template may tighten the policy, never loosen it
+		switch {
+		case rule != nil && fmMode&^rule.Mode != 0:
+			return outputPerms{}, fmt.Errorf("front matter mode %04o grants more access than the mode %04o the output policy requires for %s", fmMode, rule.Mode, filepath.ToSlash(relPath))
+		case perms.Sensitive && fmMode&0007 != 0:
+			return outputPerms{}, fmt.Errorf("front matter mode %04o makes the sensitive output %s accessible to others", fmMode, filepath.ToSlash(relPath))
+		}
+		perms.Mode = fmMode
+	}
+
+	if !perms.Sensitive {
+		if keys := secretKeysIn(content, opts.SecretValues); len(keys) > 0 {
+			perms.Warning = fmt.Sprintf("%s contains the value of secret key(s) %s but matches no sensitive output policy; writing it with mode 0400",
+				filepath.ToSlash(relPath), strings.Join(keys, ", "))
+			perms.Sensitive = true
+			perms.Mode = 0400
+		}
+	}
+	return perms, nil
+}
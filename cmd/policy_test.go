@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*.env", "org/Poppit/.env", true},
+		{"*.env", "org/Poppit/app.env", true},
+		{"*.env", "org/Poppit/.env-builder", false},
+		{"**/Poppit/.env-builder", "org/Poppit/.env-builder", true},
+		{"**/Poppit/.env-builder", "Poppit/.env-builder", true},
+		{"*/Poppit/*", "org/Poppit/config.yaml", true},
+		{"*/Poppit/*", "org/other/Poppit/config.yaml", false},
+		{"org/**/*.yaml", "org/a/b/c.yaml", true},
+		{"org/**/*.yaml", "other/a/c.yaml", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.path); got != tt.expected {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestNewOutputPolicy(t *testing.T) {
+	policy, err := newOutputPolicy(&utils.OutputPolicy{
+		SensitiveOutputs: []utils.SensitiveOutputRule{
+			{Pattern: "*.env"},
+			{Pattern: "**/Poppit/*", Mode: "0440", Owner: "0", Group: "0"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule := policy.match("org/svc/.env"); rule == nil || rule.Mode != 0400 || rule.Owner != noOwner {
+		t.Errorf("expected default mode 0400 without owner, got %+v", rule)
+	}
+	if rule := policy.match("org/Poppit/config.yaml"); rule == nil || rule.Mode != 0440 || rule.Owner != (fileOwner{UID: 0, GID: 0}) {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+	if rule := policy.match("org/svc/config.yaml"); rule != nil {
+		t.Errorf("expected no rule to match, got %+v", rule)
+	}
+}
+
+func TestNewOutputPolicy_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   utils.SensitiveOutputRule
+		errMsg string
+	}{
+		{"invalid mode", utils.SensitiveOutputRule{Pattern: "*.env", Mode: "rw"}, "invalid mode"},
+		{"world readable", utils.SensitiveOutputRule{Pattern: "*.env", Mode: "0644"}, "must not be accessible to others"},
+		{"unknown owner", utils.SensitiveOutputRule{Pattern: "*.env", Owner: "no-such-user-vibeops"}, "invalid owner"},
+		{"unknown group", utils.SensitiveOutputRule{Pattern: "*.env", Group: "no-such-group-vibeops"}, "invalid group"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newOutputPolicy(&utils.OutputPolicy{SensitiveOutputs: []utils.SensitiveOutputRule{tt.rule}})
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestSecretKeysIn(t *testing.T) {
+	values := map[string]interface{}{
+		"RedisPassword": "hunter22",
+		"SlackBotToken": "xoxb-token",
+		"Short":         "abc",
+		"Port":          6379,
+	}
+	secrets := collectSecretValues(values, []string{"RedisPassword", "Short", "Port"}, []string{"SlackBotToken"})
+	if !reflect.DeepEqual(secrets, map[string]string{"RedisPassword": "hunter22", "SlackBotToken": "xoxb-token"}) {
+		t.Errorf("unexpected secret values: %v", secrets)
+	}
+
+	keys := secretKeysIn([]byte("redis:\n  password: hunter22\n  token: xoxb-token\n"), secrets)
+	if !reflect.DeepEqual(keys, []string{"RedisPassword", "SlackBotToken"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+	if keys := secretKeysIn([]byte("abc 6379"), secrets); len(keys) != 0 {
+		t.Errorf("expected no secrets, got %v", keys)
+	}
+}
+
+func TestCollectSecretValues_Nested(t *testing.T) {
+	values := map[string]interface{}{
+		"Redis": map[string]interface{}{"Host": "localhost", "Password": "hunter22", "Port": float64(6379)},
+		"Slack": map[string]interface{}{"Tokens": []interface{}{"xoxb-first", "abc"}},
+		"Db":    map[string]interface{}{"Password": "db-password", "User": "admin-user"},
+	}
+	secrets := collectSecretValues(values, []string{"Redis", "Slack", "Db.Password", "Db.Missing", "Redis.Host.Deeper"})
+	expected := map[string]string{
+		"Redis.Host":      "localhost",
+		"Redis.Password":  "hunter22",
+		"Slack.Tokens[0]": "xoxb-first",
+		"Db.Password":     "db-password",
+	}
+	if !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("collectSecretValues() = %v, want %v", secrets, expected)
+	}
+}
+
+func TestProcessTemplates_OutputPolicy(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"svc/.env.tmpl":            "PASSWORD={{ .RedisPassword }}",
+		"svc/builder.conf.tmpl":    "PASSWORD={{ .RedisPassword }}",
+		"svc/config.yaml.tmpl":     "password: {{ .RedisPassword }}",
+		"svc/public.yaml.tmpl":     "host: {{ .RedisHost }}",
+		"svc/credentials.ini.tmpl": "token={{ .SlackBotToken }}",
+	})
+
+	policy, err := newOutputPolicy(&utils.OutputPolicy{
+		SensitiveOutputs: []utils.SensitiveOutputRule{
+			{Pattern: "*.conf", Mode: "0440"},
+		},
+		SecretKeys: []string{"RedisPassword"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]interface{}{"RedisPassword": "hunter22", "RedisHost": "localhost", "SlackBotToken": "xoxb-token"}
+	secret := &secretProvenance{Keys: map[string]string{"SlackBotToken": "1"}}
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{Policy: policy, Secret: secret}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	modes := map[string]os.FileMode{
+		// The policy replaces the default rules, so .env is only caught by secret detection
+		".env":            0400,
+		"builder.conf":    0440,
+		"config.yaml":     0400,
+		"public.yaml":     0644,
+		"credentials.ini": 0400,
+	}
+	for name, mode := range modes {
+		info, err := os.Stat(filepath.Join(buildDir, "svc", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("expected %s to have mode %o, got %o", name, mode, info.Mode().Perm())
+		}
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range manifest.Files {
+		if sensitive := entry.Path != "svc/public.yaml"; entry.Sensitive != sensitive {
+			t.Errorf("expected %s sensitive=%v, got %v", entry.Path, sensitive, entry.Sensitive)
+		}
+	}
+}
+
+func TestOutputPermissions_FrontMatterMode(t *testing.T) {
+	opts := templateOptions{SecretValues: map[string]string{"RedisPassword": "hunter22"}}
+
+	// A front-matter mode may narrow the policy's mode
+	policy, err := newOutputPolicy(&utils.OutputPolicy{SensitiveOutputs: []utils.SensitiveOutputRule{{Pattern: "*.env", Mode: "0440"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	perms, err := outputPermissions("svc/.env", []byte("x"), frontMatter{Mode: "0400"}, templateOptions{Policy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms.Mode != 0400 || !perms.Sensitive || perms.Warning != "" {
+		t.Errorf("expected front matter mode on a sensitive output, got %+v", perms)
+	}
+
+	// but not grant more access than the policy or expose a sensitive output to others
+	tests := []struct {
+		relPath string
+		fm      frontMatter
+		errMsg  string
+	}{
+		{"svc/.env", frontMatter{Mode: "0644"}, "grants more access than the mode 0400"},
+		{"svc/.env", frontMatter{Mode: "0440"}, "grants more access than the mode 0400"},
+		{"svc/config.yaml", frontMatter{Mode: "0604", Sensitive: true}, "accessible to others"},
+	}
+	for _, tt := range tests {
+		if _, err := outputPermissions(tt.relPath, []byte("x"), tt.fm, opts); err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("outputPermissions(%s, %+v): expected an error containing %q, got %v", tt.relPath, tt.fm, tt.errMsg, err)
+		}
+	}
+
+	// A secret in an output no policy covers is forced to 0400, even over an explicit mode
+	perms, err = outputPermissions("svc/config.yaml", []byte("hunter22"), frontMatter{Mode: "0644"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms.Mode != 0400 || !perms.Sensitive || !strings.Contains(perms.Warning, "RedisPassword") {
+		t.Errorf("expected secret detection to force 0400 with a warning, got %+v", perms)
+	}
+}
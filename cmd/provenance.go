@@ -0,0 +1,391 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// maxDisplayedValueLength is the length beyond which values are shortened in tables
+const maxDisplayedValueLength = 60
+
+// NewValuesCmd creates the values command and its explain and list subcommands
+func NewValuesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values",
+		Short: "Inspect the merged template values and where they come from",
+	}
+	cmd.AddCommand(newValuesExplainCmd())
+	cmd.AddCommand(newValuesListCmd())
+	return cmd
+}
+
+// newValuesExplainCmd creates the values explain subcommand
+func newValuesExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <Key>",
+		Short: "Show every layer that defines a value key and which one took effect",
+		Long: `Show every layer that defines a top-level value key, in merge order, with the type
+and value each gives it and which one took effect. Secret values are masked.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inspection, err := inspectValues(cmd)
+			if err != nil {
+				return err
+			}
+			provenance, ok := inspection.explain(args[0])
+			if !ok {
+				return fmt.Errorf("%s is not defined in any values layer", args[0])
+			}
+			return writeProvenanceText(os.Stdout, provenance)
+		},
+	}
+	addValuesInspectionFlags(cmd)
+	return cmd
+}
+
+// newValuesListCmd creates the values list subcommand
+func newValuesListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Print the fully merged values with secrets masked",
+		Long: `Print every key of the fully merged values with its type, the layer it came from and
+its value, or the merged map as JSON with --output json. Secret values, and values
+named like a password, secret, token or key, are masked.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, _ := cmd.Flags().GetString("output")
+			if output != "text" && output != "json" {
+				return fmt.Errorf("invalid --output %q: expected text or json", output)
+			}
+
+			inspection, err := inspectValues(cmd)
+			if err != nil {
+				return err
+			}
+			if output == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(inspection.redacted())
+			}
+			return inspection.writeListText(os.Stdout)
+		},
+	}
+	addValuesInspectionFlags(cmd)
+	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	return cmd
+}
+
+// addValuesInspectionFlags adds the flags selecting which values are inspected,
+// matching the template command
+func addValuesInspectionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("policy", "output-policy.json", "Sensitive output policy file listing the secret keys to mask")
+	addEnvFlag(cmd)
+	addValueOverrideFlags(cmd)
+}
+
+// valuesInspection is every layer of template values, in merge order, and the
+// values they merge to
+type valuesInspection struct {
+	// Layers are the loaded sources followed by the overrides
+	Layers []valueSource
+	// Overrides is how many of the last layers are overrides, which replace keys wholesale
+	Overrides int
+	// SchemaDefaults is set when the first layer is the schema's defaults, which only
+	// fill in missing keys
+	SchemaDefaults bool
+	Merge          utils.MergeOptions
+	Merged         map[string]interface{}
+	// SecretKeys are the keys the output policy or the values schema marks as secret
+	SecretKeys map[string]bool
+	// References are the top-level keys each value references with ${Key}
+	References map[string][]string
+}
+
+// inspectValues loads the values the way the template command does
+func inspectValues(cmd *cobra.Command) (*valuesInspection, error) {
+	policyFile, _ := cmd.Flags().GetString("policy")
+	env, err := resolveEnv(cmd)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := loadValueOverrides(cmd)
+	if err != nil {
+		return nil, err
+	}
+	set, err := loadValueSources(env)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeValueSources(set.Sources, set.Merge)
+	if err != nil {
+		return nil, err
+	}
+	merged = applyValueOverrides(io.Discard, merged, set.Sources, overrides)
+
+	// The schema's defaults fill in missing keys, so they act as the lowest layer
+	sources := set.Sources
+	schema, err := loadValuesSchema()
+	if err != nil {
+		return nil, err
+	}
+	if schema != nil {
+		sources = append([]valueSource{{Name: valuesSchemaFile + " default", Values: schema.Defaults()}}, sources...)
+	}
+	unresolved := merged
+	if schema != nil {
+		unresolved = schema.ApplyDefaults(merged)
+	}
+	literal := literalPaths(append(set.Sources, overrides...))
+	references := valueReferences(unresolved, literal)
+	if merged, err = resolveValues(merged, literal, schema); err != nil {
+		return nil, err
+	}
+
+	policy, err := utils.LoadOutputPolicy(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", policyFile, err)
+	}
+	secretKeys := make(map[string]bool, len(policy.SecretKeys))
+	for _, key := range policy.SecretKeys {
+		secretKeys[key] = true
+	}
+	if schema != nil {
+		for _, key := range schema.SecretKeys() {
+			secretKeys[key] = true
+		}
+	}
+
+	return &valuesInspection{
+		Layers:         append(sources, overrides...),
+		Overrides:      len(overrides),
+		SchemaDefaults: schema != nil,
+		Merge:          set.Merge,
+		Merged:         merged,
+		SecretKeys:     secretKeys,
+		References:     references,
+	}, nil
+}
+
+// valueProvenance explains where the value of a key comes from
+type valueProvenance struct {
+	Key       string
+	Type      string
+	Effective interface{}
+	Secret    bool
+	Layers    []layerValue
+}
+
+// layerValue is the value one layer gives a key
+type layerValue struct {
+	Layer string
+	Type  string
+	Value interface{}
+	// Status is "in effect" for the winning layer, "merged" for a layer deep merged
+	// into it and "overridden" for a layer it replaced
+	Status string
+}
+
+// explain reports every layer defining key, masking secret values; it returns
+// false when no layer defines it
+func (v *valuesInspection) explain(key string) (*valueProvenance, bool) {
+	effective, ok := v.Merged[key]
+	if !ok {
+		return nil, false
+	}
+	provenance := &valueProvenance{Key: key, Type: utils.ValueType(effective), Secret: v.isSecret(key)}
+
+	var defining []int
+	for i, layer := range v.Layers {
+		if _, ok := layer.Values[key]; ok {
+			defining = append(defining, i)
+		}
+	}
+	winner := defining[len(defining)-1]
+	for _, i := range defining {
+		layer := v.Layers[i]
+		value := layer.Values[key]
+		status := "overridden"
+		switch {
+		case i == winner:
+			status = "in effect"
+		case v.mergedInto(i, winner, value, effective):
+			status = "merged"
+		}
+		if layer.Secret || v.secretKey(key) {
+			value = redactedValue
+		}
+		provenance.Layers = append(provenance.Layers, layerValue{Layer: layer.Name, Type: utils.ValueType(layer.Values[key]), Value: maskSecretFields(value), Status: status})
+	}
+
+	provenance.Effective = maskSecretFields(effective)
+	if provenance.Secret {
+		provenance.Effective = redactedValue
+	}
+	return provenance, true
+}
+
+// mergedInto reports whether the value layer i gives a key was deep merged into
+// the effective value rather than replaced by the winning layer
+func (v *valuesInspection) mergedInto(i, winner int, value, effective interface{}) bool {
+	overridesStart := len(v.Layers) - v.Overrides
+	if v.Merge.Mode != utils.MergeDeep || winner >= overridesStart || v.SchemaDefaults && i == 0 {
+		return false
+	}
+	switch utils.ValueType(effective) {
+	case "map":
+		return utils.ValueType(value) == "map"
+	case "list":
+		return utils.ValueType(value) == "list" && v.Merge.Lists != "" && v.Merge.Lists != utils.ListReplace
+	}
+	return false
+}
+
+// isSecret reports whether a key's value must be masked: the output policy or the
+// values schema marks it, its name looks secret, a secret layer defines it or it
+// references a secret value
+func (v *valuesInspection) isSecret(key string) bool {
+	return v.referencesSecret(key, make(map[string]bool))
+}
+
+// referencesSecret reports whether key is secret itself or references a secret
+// value, directly or through other references. seen guards against cycles.
+func (v *valuesInspection) referencesSecret(key string, seen map[string]bool) bool {
+	if seen[key] {
+		return false
+	}
+	seen[key] = true
+	if v.secretKey(key) {
+		return true
+	}
+	for _, layer := range v.Layers {
+		if _, ok := layer.Values[key]; ok && layer.Secret {
+			return true
+		}
+	}
+	for _, ref := range v.References[key] {
+		if v.referencesSecret(ref, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretKey reports whether key is marked secret or named like a secret
+func (v *valuesInspection) secretKey(key string) bool {
+	return v.SecretKeys[key] || secretNamePattern.MatchString(key)
+}
+
+// maskSecretFields returns value with the values of nested keys named like a
+// secret masked, e.g. the Password of a Redis map
+func maskSecretFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if secretNamePattern.MatchString(key) {
+				item = redactedValue
+			}
+			masked[key] = maskSecretFields(item)
+		}
+		return masked
+	case []map[string]interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskSecretFields(item)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskSecretFields(item)
+		}
+		return masked
+	}
+	return value
+}
+
+// source returns the layer the effective value of key came from, or "merged"
+// when several layers were deep merged into it
+func (v *valuesInspection) source(key string) string {
+	provenance, ok := v.explain(key)
+	if !ok {
+		return ""
+	}
+	for _, layer := range provenance.Layers {
+		if layer.Status == "merged" {
+			return "merged"
+		}
+	}
+	return provenance.Layers[len(provenance.Layers)-1].Layer
+}
+
+// redacted returns the merged values with secret values masked
+func (v *valuesInspection) redacted() map[string]interface{} {
+	redacted := make(map[string]interface{}, len(v.Merged))
+	for key, value := range v.Merged {
+		if v.isSecret(key) {
+			value = redactedValue
+		}
+		redacted[key] = maskSecretFields(value)
+	}
+	return redacted
+}
+
+// writeListText writes a table of every merged key with its type, source and value
+func (v *valuesInspection) writeListText(w io.Writer) error {
+	values := v.redacted()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tTYPE\tSOURCE\tVALUE")
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", key, utils.ValueType(v.Merged[key]), v.source(key), displayValue(values[key]))
+	}
+	return tw.Flush()
+}
+
+// writeProvenanceText writes the explanation of a key as a summary and a table of layers
+func writeProvenanceText(w io.Writer, p *valueProvenance) error {
+	secret := ""
+	if p.Secret {
+		secret = " (secret)"
+	}
+	fmt.Fprintf(w, "%s%s\n", p.Key, secret)
+	fmt.Fprintf(w, "Type:  %s\n", p.Type)
+	fmt.Fprintf(w, "Value: %s\n\n", displayValue(p.Effective))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LAYER\tTYPE\tVALUE\tSTATUS")
+	for _, layer := range p.Layers {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", layer.Layer, layer.Type, displayValue(layer.Value), layer.Status)
+	}
+	return tw.Flush()
+}
+
+// displayValue formats a value for a table: masked values and strings as they
+// are, anything else as compact JSON, shortened when long
+func displayValue(value interface{}) string {
+	var display string
+	if str, ok := value.(string); ok {
+		display = str
+	} else if data, err := json.Marshal(value); err == nil {
+		display = string(data)
+	} else {
+		display = fmt.Sprint(value)
+	}
+	if runes := []rune(display); len(runes) > maxDisplayedValueLength {
+		display = string(runes[:maxDisplayedValueLength-1]) + "…"
+	}
+	return display
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// newTestInspection merges sources and overrides the way inspectValues does
+func newTestInspection(t *testing.T, sources, overrides []valueSource, opts utils.MergeOptions, secretKeys ...string) *valuesInspection {
+	t.Helper()
+	merged, err := mergeValueSources(sources, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report bytes.Buffer
+	inspection := &valuesInspection{
+		Layers:     append(sources, overrides...),
+		Overrides:  len(overrides),
+		Merge:      opts,
+		Merged:     applyValueOverrides(&report, merged, sources, overrides),
+		SecretKeys: make(map[string]bool),
+	}
+	for _, key := range secretKeys {
+		inspection.SecretKeys[key] = true
+	}
+	return inspection
+}
+
+func TestValuesInspectionExplain(t *testing.T) {
+	sources := []valueSource{
+		{Name: "values.json", Values: map[string]interface{}{
+			"OrgName":       "org",
+			"Redis":         map[string]interface{}{"Host": "localhost"},
+			"RedisPassword": "local",
+			"ApiKey":        "key",
+		}},
+		{Name: "ports.json", Values: map[string]interface{}{"Redis": map[string]interface{}{"Port": float64(6379)}}},
+		{Name: "GCP secret s", Values: map[string]interface{}{"RedisPassword": "hunter2"}, Secret: true},
+	}
+	overrides := []valueSource{{Name: "--set", Values: map[string]interface{}{"OrgName": "acme"}}}
+	inspection := newTestInspection(t, sources, overrides, utils.MergeOptions{Mode: utils.MergeDeep}, "ApiKey")
+
+	tests := []struct {
+		key      string
+		expected *valueProvenance
+	}{
+		{"OrgName", &valueProvenance{Key: "OrgName", Type: "string", Effective: "acme", Layers: []layerValue{
+			{Layer: "values.json", Type: "string", Value: "org", Status: "overridden"},
+			{Layer: "--set", Type: "string", Value: "acme", Status: "in effect"},
+		}}},
+		{"Redis", &valueProvenance{Key: "Redis", Type: "map", Effective: map[string]interface{}{"Host": "localhost", "Port": float64(6379)}, Layers: []layerValue{
+			{Layer: "values.json", Type: "map", Value: map[string]interface{}{"Host": "localhost"}, Status: "merged"},
+			{Layer: "ports.json", Type: "map", Value: map[string]interface{}{"Port": float64(6379)}, Status: "in effect"},
+		}}},
+		{"RedisPassword", &valueProvenance{Key: "RedisPassword", Type: "string", Effective: redactedValue, Secret: true, Layers: []layerValue{
+			{Layer: "values.json", Type: "string", Value: redactedValue, Status: "overridden"},
+			{Layer: "GCP secret s", Type: "string", Value: redactedValue, Status: "in effect"},
+		}}},
+		{"ApiKey", &valueProvenance{Key: "ApiKey", Type: "string", Effective: redactedValue, Secret: true, Layers: []layerValue{
+			{Layer: "values.json", Type: "string", Value: redactedValue, Status: "in effect"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			provenance, ok := inspection.explain(tt.key)
+			if !ok || !reflect.DeepEqual(provenance, tt.expected) {
+				t.Errorf("explain(%q) = %+v, %v, want %+v", tt.key, provenance, ok, tt.expected)
+			}
+		})
+	}
+
+	if _, ok := inspection.explain("Missing"); ok {
+		t.Error("expected an undefined key not to be explained")
+	}
+}
+
+func TestValuesInspectionList(t *testing.T) {
+	sources := []valueSource{
+		{Name: "values.json", Values: map[string]interface{}{"OrgName": "org", "Redis": map[string]interface{}{"Host": "localhost"}}},
+		{Name: "GCP secret s", Values: map[string]interface{}{"RedisPassword": "hunter2", "Redis": map[string]interface{}{"Password": "pw"}}, Secret: true},
+	}
+	inspection := newTestInspection(t, sources, nil, utils.MergeOptions{Mode: utils.MergeDeep})
+
+	expected := map[string]interface{}{"OrgName": "org", "Redis": redactedValue, "RedisPassword": redactedValue}
+	if redacted := inspection.redacted(); !reflect.DeepEqual(redacted, expected) {
+		t.Errorf("redacted() = %v, want %v", redacted, expected)
+	}
+
+	var out bytes.Buffer
+	if err := inspection.writeListText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"OrgName        string  values.json   org",
+		"Redis          map     merged        <redacted>",
+		"RedisPassword  string  GCP secret s  <redacted>",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected a line %q in:\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "hunter2") || strings.Contains(out.String(), "pw") {
+		t.Errorf("secret value leaked in:\n%s", out.String())
+	}
+}
+
+func TestValuesInspection_SecretNames(t *testing.T) {
+	// The stock values.json.example, without any output policy or values schema
+	values, err := os.ReadFile(filepath.Join("..", "values.json.example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":   string(values),
+		"projects.json": `[{"name": "Poppit", "vibeIndex": {"name": "Poppit", "portKey": "PoppitPort"}}]`,
+		"ports.json":    `{"Redis": {"Host": "localhost", "Password": "nested-password"}}`,
+	})
+
+	cmd := newValuesListCmd()
+	inspection, err := inspectValues(cmd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := inspection.writeListText(&out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(inspection.redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, output := range []string{out.String(), string(data)} {
+		for _, secret := range []string{"example-redis-password", "example-slack-webhook-secret", "example-github-webhook-secret", "example-slack-bot-token", "nested-password"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("secret value %q leaked in:\n%s", secret, output)
+			}
+		}
+	}
+	for _, shown := range []string{"its-the-vibe", "PoppitPort", "localhost"} {
+		if !strings.Contains(string(data), shown) {
+			t.Errorf("expected %q to be shown in:\n%s", shown, data)
+		}
+	}
+
+	provenance, ok := inspection.explain("RedisPassword")
+	if !ok || !provenance.Secret || provenance.Effective != redactedValue || provenance.Layers[0].Value != redactedValue {
+		t.Errorf("explain(RedisPassword) = %+v, want it masked", provenance)
+	}
+}
+
+func TestValuesInspection_ReferencedSecret(t *testing.T) {
+	sources := []valueSource{
+		{Name: "values.json", Values: map[string]interface{}{
+			"RedisHost": "localhost",
+			"RedisURL":  "redis://:${RedisPassword}@${RedisHost}",
+			"CacheURL":  "${RedisURL}/1",
+		}},
+		{Name: "GCP secret s", Values: map[string]interface{}{"RedisPassword": "hunter2"}, Secret: true},
+	}
+	inspection := newTestInspection(t, sources, nil, utils.MergeOptions{})
+	inspection.References = map[string][]string{"RedisURL": {"RedisHost", "RedisPassword"}, "CacheURL": {"RedisURL"}}
+
+	for key, secret := range map[string]bool{"RedisHost": false, "RedisPassword": true, "RedisURL": true, "CacheURL": true} {
+		if got := inspection.isSecret(key); got != secret {
+			t.Errorf("isSecret(%q) = %v, want %v", key, got, secret)
+		}
+	}
+}
+
+func TestDisplayValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{"plain", "plain"},
+		{float64(8080), "8080"},
+		{map[string]interface{}{"a": true}, `{"a":true}`},
+		{strings.Repeat("x", 70), strings.Repeat("x", 59) + "…"},
+	}
+	for _, tt := range tests {
+		if got := displayValue(tt.value); got != tt.expected {
+			t.Errorf("displayValue(%v) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// findStaleOutputs returns the build-relative paths of files in dir that the
+// current run did not generate. Files the manifest attributes to a source layer
+// that was not rendered are left alone, as is the manifest itself.
+func findStaleOutputs(dir string, layers []string, result *buildResult, manifest *buildManifest) ([]string, error) {
+	generated := make(map[string]bool, len(result.Outputs))
+	for _, out := range result.Outputs {
+		generated[filepath.ToSlash(out.RelPath)] = true
+	}
+
+	rendered := layerSet(layers)
+	otherLayers := make(map[string]bool)
+	if manifest != nil {
+		for _, entry := range manifest.Files {
+			if !rendered[path.Clean(entry.Layer)] {
+				otherLayers[entry.Path] = true
+			}
+		}
+	}
+
+	var stale []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if relPath == manifestFileName || generated[relPath] || otherLayers[relPath] {
+			return nil
+		}
+		stale = append(stale, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(stale)
+	return stale, nil
+}
+
+// movedOutputs returns the build-relative paths the manifest attributes to the
+// templates in only that the current run did not generate again, such as the old
+// output of a template whose front-matter path changed
+func movedOutputs(manifest *buildManifest, only map[string]bool, result *buildResult) []string {
+	if manifest == nil {
+		return nil
+	}
+	rendered := make(map[string]bool, len(only))
+	for path := range only {
+		rendered[filepath.Clean(path)] = true
+	}
+	generated := make(map[string]bool, len(result.Outputs))
+	for _, out := range result.Outputs {
+		generated[filepath.ToSlash(out.RelPath)] = true
+	}
+
+	var moved []string
+	for _, entry := range manifest.Files {
+		source := filepath.Join(filepath.FromSlash(entry.Layer), filepath.FromSlash(entry.Source))
+		if rendered[source] && !generated[entry.Path] {
+			moved = append(moved, entry.Path)
+		}
+	}
+	sort.Strings(moved)
+	return moved
+}
+
+// pruneOutputs deletes the given build-relative files from dir, then removes
+// any directories left empty by the deletions
+func pruneOutputs(dir string, stale []string) error {
+	parents := make(map[string]bool)
+	for _, relPath := range stale {
+		path := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to prune %s: %w", path, err)
+		}
+		for parent := filepath.Dir(relPath); parent != "."; parent = filepath.Dir(parent) {
+			parents[parent] = true
+		}
+	}
+
+	// Remove the deepest directories first so emptied parents can be removed too
+	dirs := make([]string, 0, len(parents))
+	for parent := range parents {
+		dirs = append(dirs, parent)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+
+	for _, relDir := range dirs {
+		path := filepath.Join(dir, filepath.FromSlash(relDir))
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to read directory %s: %w", path, err)
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to prune empty directory %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessTemplates_Prune(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"org/Kept/config.json.tmpl":    `{"kept": true}`,
+		"org/Removed/config.json.tmpl": `{"removed": true}`,
+	})
+	if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(sourceDir, "org", "Removed", "config.json.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dry run only lists the stale file
+	result, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{PruneDryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Pruned) != 0 {
+		t.Errorf("expected dry run not to prune, got %v", result.Pruned)
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "org", "Removed", "config.json")); err != nil {
+		t.Errorf("expected dry run to keep stale file: %v", err)
+	}
+
+	// An empty directory the prune did not empty is left alone
+	if err := os.MkdirAll(filepath.Join(buildDir, "org", "Empty", "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "org", "Empty", "nested")); err != nil {
+		t.Errorf("expected unrelated empty directory to be kept: %v", err)
+	}
+	if !reflect.DeepEqual(result.Pruned, []string{"org/Removed/config.json"}) {
+		t.Errorf("unexpected pruned files: %v", result.Pruned)
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "org", "Removed")); !os.IsNotExist(err) {
+		t.Errorf("expected emptied directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "org", "Kept", "config.json")); err != nil {
+		t.Errorf("expected generated file to be kept: %v", err)
+	}
+	if got := result.Summary(); got != "0 created, 0 updated, 1 unchanged, 1 pruned" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestProcessTemplates_PruneKeepsOtherLayers(t *testing.T) {
+	dir := t.TempDir()
+	publicDir := filepath.Join(dir, "source")
+	privateDir := filepath.Join(dir, "source-private")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, publicDir, map[string]string{"org/Public/config.json.tmpl": `{}`})
+	writeTestFiles(t, privateDir, map[string]string{"org/Private/config.json.tmpl": `{}`})
+
+	for _, sourceDir := range []string{publicDir, privateDir} {
+		if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// A file no layer generated is stale; the private layer's output is not
+	if err := os.WriteFile(filepath.Join(buildDir, "org", "leftover.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := processTemplates([]string{publicDir}, buildDir, map[string]interface{}{}, templateOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Pruned, []string{"org/leftover.txt"}) {
+		t.Errorf("unexpected pruned files: %v", result.Pruned)
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "org", "Private", "config.json")); err != nil {
+		t.Errorf("expected other layer's output to be kept: %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secret values in redacted previews
+const redactedValue = "<redacted>"
+
+// secretNamePattern matches the names of keys that hold secrets by convention,
+// e.g. RedisPassword, SlackBotToken or ApiKey, which are masked even when no
+// policy or schema marks them
+var secretNamePattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|(api|access|private|signing|encryption)_?key)`)
+
+// NewRenderCmd creates the render command
+func NewRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render <path-to-tmpl>",
+		Short: "Render a single template to stdout",
+		Long:  `Render one template with the same merged values as the template command and print the result to stdout, without writing anything to the build folder.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceDirs, _ := cmd.Flags().GetStringSlice("source-dir")
+			followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+			strict, _ := cmd.Flags().GetBool("strict")
+			project, _ := cmd.Flags().GetString("project")
+			redact, _ := cmd.Flags().GetBool("redact")
+			format, _ := cmd.Flags().GetBool("format")
+			policyFile, _ := cmd.Flags().GetString("policy")
+
+			env, err := resolveEnv(cmd)
+			if err != nil {
+				return err
+			}
+			overrides, err := loadValueOverrides(cmd)
+			if err != nil {
+				return err
+			}
+			schema, err := loadValuesSchema()
+			if err != nil {
+				return err
+			}
+			values, secret, err := loadTemplateValues(env, overrides, schema)
+			if err != nil {
+				return err
+			}
+
+			opts := renderOptions{
+				SourceDirs:     sourceDirs,
+				FollowSymlinks: followSymlinks,
+				Strict:         strict,
+				Project:        project,
+				Format:         format,
+			}
+			if redact {
+				policy, err := utils.LoadOutputPolicy(policyFile)
+				if err != nil {
+					return fmt.Errorf("error loading %s: %w", policyFile, err)
+				}
+				opts.SecretKeys = policy.SecretKeys
+				if schema != nil {
+					opts.SecretKeys = append(opts.SecretKeys, schema.SecretKeys()...)
+				}
+				if secret != nil {
+					for key := range secret.Keys {
+						opts.SecretKeys = append(opts.SecretKeys, key)
+					}
+				}
+				opts.SecretKeys = append(opts.SecretKeys, secretNamedKeys(values)...)
+				opts.Redact = true
+			}
+
+			output, err := renderPreview(args[0], values, opts)
+			if err != nil {
+				return fmt.Errorf("error rendering %s: %w", args[0], err)
+			}
+			_, err = cmd.OutOrStdout().Write(output)
+			return err
+		},
+	}
+
+	cmd.Flags().StringSliceP("source-dir", "s", []string{"source"}, "Source directories to load shared partials from")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symlinks in the source directories when loading partials")
+	cmd.Flags().Bool("strict", false, "Fail on value keys missing from the merged values, reporting every missing key")
+	addValueOverrideFlags(cmd)
+	cmd.Flags().String("project", "", "Project to bind to .Project when previewing a __each.Projects__ template")
+	cmd.Flags().Bool("redact", false, "Mask the values of secret keys in the output")
+	cmd.Flags().Bool("format", false, "Pretty-print JSON and YAML output")
+	cmd.Flags().String("policy", "output-policy.json", "Sensitive output policy file listing the secret keys to redact")
+	addEnvFlag(cmd)
+	return cmd
+}
+
+// renderOptions controls how renderPreview renders a single template
+type renderOptions struct {
+	// SourceDirs are the source layers partials are loaded from and the template's path is resolved against
+	SourceDirs     []string
+	FollowSymlinks bool
+	Strict         bool
+	// Project names the project bound to .Project in a fan-out template
+	Project string
+	// Redact masks the values of SecretKeys in the output
+	Redact     bool
+	SecretKeys []string
+	// Format pretty-prints JSON and YAML output
+	Format bool
+}
+
+// renderPreview renders the template at srcPath in memory, as the template command
+// would, and returns the (optionally redacted and formatted) output
+func renderPreview(srcPath string, values map[string]interface{}, opts renderOptions) ([]byte, error) {
+	content, err := os.ReadFile(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file: %w", err)
+	}
+	relPath := previewRelPath(srcPath, opts.SourceDirs)
+	if isPartialPath(relPath) {
+		return nil, fmt.Errorf("%s is a shared partial and is never rendered on its own", srcPath)
+	}
+
+	partials, err := loadPartials(opts.SourceDirs, opts.FollowSymlinks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load partials: %w", err)
+	}
+	parsed, err := parseTemplate(srcPath, content, partials)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work out the output path the template would be written to, binding .Project for fan-out templates
+	instances, err := expandFanOut(relPath, values)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := selectFanOutInstance(relPath, instances, opts.Project)
+	if err != nil {
+		return nil, err
+	}
+	if inst.Project != nil {
+		values = withProject(values, inst.Project)
+	}
+	expandedRelPath := expandPathVars(inst.RelPath, values)
+	if serviceDir := serviceDirOf(expandedRelPath); serviceDir != "" {
+		values = withService(values, serviceDir)
+	}
+
+	fm := parsed.FrontMatter
+	render, err := fm.evalWhen(srcPath, values, opts.Strict)
+	if err != nil {
+		return nil, err
+	}
+	if !render {
+		fmt.Fprintf(os.Stderr, "Note: front matter when %q is false, so the template command skips this template\n", fm.When)
+	}
+	outputRelPath, err := fm.outputRelPath(srcPath, strings.TrimSuffix(expandedRelPath, ".tmpl"), values, opts.Strict)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := parsed.execute(values, templateOptions{Strict: opts.Strict})
+	if err != nil {
+		return nil, err
+	}
+	output := rendered.Content
+
+	if opts.Redact {
+		output = redactSecrets(output, values, opts.SecretKeys)
+	}
+	if opts.Format {
+		if output, err = formatOutput(output, outputRelPath); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+// previewRelPath returns srcPath relative to the first source layer containing it,
+// or its base name when it lies outside every layer
+func previewRelPath(srcPath string, sourceDirs []string) string {
+	absPath, err := filepath.Abs(srcPath)
+	if err != nil {
+		return filepath.Base(srcPath)
+	}
+	for _, sourceDir := range sourceDirs {
+		absDir, err := filepath.Abs(sourceDir)
+		if err != nil {
+			continue
+		}
+		if relPath, err := filepath.Rel(absDir, absPath); err == nil && filepath.IsLocal(relPath) {
+			return relPath
+		}
+	}
+	return filepath.Base(srcPath)
+}
+
+// selectFanOutInstance picks the instance for project, which is required when the path fans out
+func selectFanOutInstance(relPath string, instances []fanOutInstance, project string) (fanOutInstance, error) {
+	if len(instances) == 1 && instances[0].Project == nil {
+		if project != "" {
+			return fanOutInstance{}, fmt.Errorf("--project was given but %s is not a __each.Projects__ template", relPath)
+		}
+		return instances[0], nil
+	}
+
+	names := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		name, _ := inst.Project["name"].(string)
+		if name == project {
+			return inst, nil
+		}
+		names = append(names, name)
+	}
+	if project == "" {
+		return fanOutInstance{}, fmt.Errorf("%s renders once per project; choose one with --project (one of: %s)", relPath, strings.Join(names, ", "))
+	}
+	return fanOutInstance{}, fmt.Errorf("project %q does not match %s (expected one of: %s)", project, relPath, strings.Join(names, ", "))
+}
+
+// redactSecrets replaces every occurrence of the values of keys in content, as
+// written or escaped in a JSON string by toJson or quote. Like the output policy,
+// it masks the strings nested in a secret map or list and ignores values shorter
+// than minSecretValueLength.
+func redactSecrets(content []byte, values map[string]interface{}, keys []string) []byte {
+	seen := make(map[string]bool)
+	var secrets []string
+	for _, value := range collectSecretValues(values, keys) {
+		for _, form := range secretForms(value) {
+			if !seen[form] {
+				seen[form] = true
+				secrets = append(secrets, form)
+			}
+		}
+	}
+	// Replace longer values first so a secret containing another is masked whole
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	for _, secret := range secrets {
+		content = bytes.ReplaceAll(content, []byte(secret), []byte(redactedValue))
+	}
+	return content
+}
+
+// secretNamedKeys returns the dotted paths of the values whose keys are named
+// like a secret, in nested maps too, e.g. "RedisPassword" and "Redis.Password"
+func secretNamedKeys(values map[string]interface{}) []string {
+	var keys []string
+	for key, value := range values {
+		if secretNamePattern.MatchString(key) {
+			keys = append(keys, key)
+			continue
+		}
+		if m, ok := value.(map[string]interface{}); ok {
+			for _, nested := range secretNamedKeys(m) {
+				keys = append(keys, key+"."+nested)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretForms returns a secret value as written and as escaped inside a JSON
+// string, with and without HTML escaping
+func secretForms(value string) []string {
+	forms := []string{value}
+	if data, err := json.Marshal(value); err == nil {
+		forms = append(forms, string(data[1:len(data)-1]))
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err == nil {
+		data := bytes.TrimSpace(buf.Bytes())
+		forms = append(forms, string(data[1:len(data)-1]))
+	}
+	return forms
+}
+
+// formatOutput pretty-prints JSON and YAML content according to the output file
+// extension; other content is returned unchanged
+func formatOutput(content []byte, outputPath string) ([]byte, error) {
+	switch {
+	case strings.HasSuffix(outputPath, ".json"):
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, content, "", "  "); err != nil {
+			return nil, utils.FormatJSONError(outputPath, err)
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	case strings.HasSuffix(outputPath, ".yaml") || strings.HasSuffix(outputPath, ".yml"):
+		var node yaml.Node
+		if err := yaml.Unmarshal(content, &node); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML in %s: %w", outputPath, err)
+		}
+		if node.Kind == 0 {
+			return content, nil
+		}
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return nil, fmt.Errorf("failed to format YAML for %s: %w", outputPath, err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to format YAML for %s: %w", outputPath, err)
+		}
+		return buf.Bytes(), nil
+	}
+	return content, nil
+}
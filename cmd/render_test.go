@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderPreview(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{
+		"_partials/name.tmpl":                     `{{ define "name" }}{{ .Project.name }}{{ end }}`,
+		"svc/config.json.tmpl":                    `{"org":"{{ .OrgName }}","token":"{{ .ApiToken }}"}`,
+		"svc/app.yaml.tmpl":                       "app:\n    name: {{ .OrgName }}\n    ports: [1, 2]\n",
+		"svc/skipped.txt.tmpl":                    "---\nwhen: .Missing\n---\nhello",
+		"__each.Projects__/deploy.txt.tmpl":       `{{ template "name" . }}`,
+		"__each.Projects__/_partials/ignore.tmpl": ``,
+	})
+	values := map[string]interface{}{
+		"OrgName":  "org",
+		"ApiToken": "s3cr3t-token",
+		"Projects": []map[string]interface{}{{"name": "Alpha"}, {"name": "Beta"}},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		opts     renderOptions
+		expected string
+		errMsg   string
+	}{
+		{name: "plain", path: "svc/config.json.tmpl", expected: `{"org":"org","token":"s3cr3t-token"}`},
+		{name: "redacted", path: "svc/config.json.tmpl", opts: renderOptions{Redact: true, SecretKeys: []string{"ApiToken"}}, expected: `{"org":"org","token":"<redacted>"}`},
+		{name: "formatted json", path: "svc/config.json.tmpl", opts: renderOptions{Format: true}, expected: "{\n  \"org\": \"org\",\n  \"token\": \"s3cr3t-token\"\n}\n"},
+		{name: "formatted yaml", path: "svc/app.yaml.tmpl", opts: renderOptions{Format: true}, expected: "app:\n  name: org\n  ports: [1, 2]\n"},
+		{name: "when false still renders", path: "svc/skipped.txt.tmpl", expected: "hello"},
+		{name: "fan-out with project", path: "__each.Projects__/deploy.txt.tmpl", opts: renderOptions{Project: "Beta"}, expected: "Beta"},
+		{name: "fan-out without project", path: "__each.Projects__/deploy.txt.tmpl", errMsg: "choose one with --project (one of: Alpha, Beta)"},
+		{name: "unknown project", path: "__each.Projects__/deploy.txt.tmpl", opts: renderOptions{Project: "Gamma"}, errMsg: `project "Gamma" does not match`},
+		{name: "project without fan-out", path: "svc/config.json.tmpl", opts: renderOptions{Project: "Alpha"}, errMsg: "is not a __each.Projects__ template"},
+		{name: "partial", path: "__each.Projects__/_partials/ignore.tmpl", errMsg: "is a shared partial"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.SourceDirs = []string{sourceDir}
+			output, err := renderPreview(filepath.Join(sourceDir, tt.path), values, tt.opts)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(output) != tt.expected {
+				t.Errorf("renderPreview() = %q, want %q", output, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	values := map[string]interface{}{
+		"Short":  "hunter",
+		"Long":   "hunter2-long",
+		"Tiny":   "abc",
+		"Count":  3,
+		"Quoted": `pa"ss<word>`,
+		"Redis":  map[string]interface{}{"Password": "redis-pass", "Hosts": []interface{}{"cache.internal"}},
+	}
+	keys := []string{"Short", "Long", "Tiny", "Count", "Missing", "Quoted", "Redis"}
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"longest first", "hunter2-long hunter", "<redacted> <redacted>"},
+		{"too short", "abc 3", "abc 3"},
+		{"nested", "redis://:redis-pass@cache.internal", "redis://:<redacted>@<redacted>"},
+		{"as written", `pa"ss<word>`, "<redacted>"},
+		{"toJson", `{"p":"pa\"ss\u003cword\u003e"}`, `{"p":"<redacted>"}`},
+		{"quote", `p: "pa\"ss<word>"`, `p: "<redacted>"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactSecrets([]byte(tt.content), values, keys); string(got) != tt.expected {
+				t.Errorf("redactSecrets(%q) = %q, want %q", tt.content, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSetValues(t *testing.T) {
+	got, err := parseSetValues([]string{"OrgName=acme", "Url=http://x/?a=b", "Empty="})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["OrgName"] != "acme" || got["Url"] != "http://x/?a=b" || got["Empty"] != "" {
+		t.Errorf("parseSetValues() = %v", got)
+	}
+
+	if _, err := parseSetValues([]string{"NoEquals"}); err == nil {
+		t.Error("expected an error for a value without =")
+	}
+}
+
+func TestRenderCmd_RedactWithoutPolicy(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":          `{"OrgName": "org", "RedisPassword": "example-redis-password", "Redis": {"Password": "nested-password"}}`,
+		"projects.json":        `[]`,
+		"source/svc/.env.tmpl": "ORG={{ .OrgName }}\nREDIS_PASSWORD={{ .RedisPassword }}\nNESTED={{ .Redis.Password }}\n",
+	})
+
+	var out bytes.Buffer
+	cmd := NewRenderCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{filepath.Join("source", "svc", ".env.tmpl"), "--redact"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "ORG=org\nREDIS_PASSWORD=<redacted>\nNESTED=<redacted>\n"
+	if out.String() != expected {
+		t.Errorf("render --redact printed %q, want %q", out.String(), expected)
+	}
+}
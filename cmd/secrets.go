@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewSecretsCmd creates the secrets command managing the encrypted secret files
+// read by the "file" secret provider
+func NewSecretsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Create and read encrypted secret files for the file secret provider",
+	}
+	cmd.AddCommand(newSecretsKeygenCmd())
+	cmd.AddCommand(newSecretsEncryptCmd())
+	cmd.AddCommand(newSecretsDecryptCmd())
+	return cmd
+}
+
+// newSecretsKeygenCmd creates the secrets keygen subcommand
+func newSecretsKeygenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "keygen",
+		Short: "Print a new random key for encrypting secret files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := utils.GenerateSecretKey()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), key)
+			return nil
+		},
+	}
+}
+
+// newSecretsEncryptCmd creates the secrets encrypt subcommand
+func newSecretsEncryptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encrypt <secrets.json>",
+		Short: "Encrypt a JSON object of secret values",
+		Long: `Encrypt a JSON object of secret values with the key from --key-file, --key-env or
+$` + utils.SecretKeyEnv + `, writing <secrets.json>.enc unless --output is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				output = args[0] + ".enc"
+			}
+			key, err := secretsKey(cmd)
+			if err != nil {
+				return err
+			}
+
+			plaintext, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			// The file provider only accepts a JSON object, so refuse anything else now
+			var values map[string]interface{}
+			if err := json.Unmarshal(plaintext, &values); err != nil {
+				return utils.FormatJSONError(args[0], err)
+			}
+
+			encrypted, err := utils.EncryptSecret(plaintext, key)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(output, encrypted, 0600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Encrypted %d values into %s\n", len(values), output)
+			return nil
+		},
+	}
+	addSecretsKeyFlags(cmd)
+	cmd.Flags().StringP("output", "o", "", "Encrypted file to write (default \"<secrets.json>.enc\")")
+	return cmd
+}
+
+// newSecretsDecryptCmd creates the secrets decrypt subcommand
+func newSecretsDecryptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decrypt <secrets.json.enc>",
+		Short: "Print the decrypted contents of an encrypted secret file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := secretsKey(cmd)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			plaintext, err := utils.DecryptSecret(data, key)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(plaintext)
+			return err
+		},
+	}
+	addSecretsKeyFlags(cmd)
+	return cmd
+}
+
+// addSecretsKeyFlags adds the flags selecting where the key is read from, as
+// KeyFile and KeyEnv do in bootstrap.json
+func addSecretsKeyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("key-file", "", "File holding the key")
+	cmd.Flags().String("key-env", "", "Environment variable holding the key (default $"+utils.SecretKeyEnv+")")
+}
+
+// secretsKey reads the key selected by --key-file or --key-env
+func secretsKey(cmd *cobra.Command) (string, error) {
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	keyEnv, _ := cmd.Flags().GetString("key-env")
+	return utils.ReadSecretKey(keyFile, keyEnv)
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// runSecretsCmd runs the secrets command with args, returning what it printed
+func runSecretsCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := NewSecretsCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestSecretsCmd_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	key, err := runSecretsCmd(t, "keygen")
+	if err != nil {
+		t.Fatalf("keygen: %v", err)
+	}
+	keyFile := filepath.Join(dir, "secret.key")
+	plainFile := filepath.Join(dir, "secrets.json")
+	plaintext := `{"RedisPassword": "hunter2", "SlackBotToken": "xoxb-1"}`
+	writeTestFiles(t, dir, map[string]string{"secret.key": key, "secrets.json": plaintext})
+
+	out, err := runSecretsCmd(t, "encrypt", plainFile, "--key-file", keyFile)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	encryptedFile := plainFile + ".enc"
+	if !strings.Contains(out, "Encrypted 2 values into "+encryptedFile) {
+		t.Errorf("unexpected encrypt output %q", out)
+	}
+	encrypted, err := os.ReadFile(encryptedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encrypted), "hunter2") {
+		t.Fatal("expected the secrets to be encrypted")
+	}
+
+	if out, err = runSecretsCmd(t, "decrypt", encryptedFile, "--key-file", keyFile); err != nil || out != plaintext {
+		t.Errorf("decrypt = %q, %v, want %q", out, err, plaintext)
+	}
+
+	// The file written by encrypt is what the file secret provider loads
+	provider, err := utils.NewSecretProvider(utils.SecretProviderConfig{Type: "file", Path: encryptedFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := provider.Load(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"RedisPassword": "hunter2", "SlackBotToken": "xoxb-1"}
+	if !reflect.DeepEqual(secret.Values, expected) {
+		t.Errorf("Values = %v, want %v", secret.Values, expected)
+	}
+}
+
+func TestSecretsCmd_DecryptErrors(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := utils.GenerateSecretKey()
+	otherKey, _ := utils.GenerateSecretKey()
+	encrypted, err := utils.EncryptSecret([]byte(`{"RedisPassword": "hunter2"}`), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip one bit of the sealed ciphertext, keeping the file well formed
+	header, body, _ := strings.Cut(string(encrypted), "\n")
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 0x01
+	tampered := header + "\n" + base64.StdEncoding.EncodeToString(sealed) + "\n"
+	writeTestFiles(t, dir, map[string]string{
+		"secrets.json.enc":  string(encrypted),
+		"tampered.json.enc": tampered,
+		"plain.json":        `[1, 2]`,
+	})
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		message string
+	}{
+		{"wrong key", []string{"decrypt", filepath.Join(dir, "secrets.json.enc")}, otherKey, "wrong key"},
+		{"tampered ciphertext", []string{"decrypt", filepath.Join(dir, "tampered.json.enc")}, key, "wrong key"},
+		{"missing key", []string{"decrypt", filepath.Join(dir, "secrets.json.enc")}, "", "set $" + utils.SecretKeyEnv},
+		{"not a JSON object", []string{"encrypt", filepath.Join(dir, "plain.json")}, key, "plain.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(utils.SecretKeyEnv, tt.env)
+			out, err := runSecretsCmd(t, tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected an error containing %q, got %v", tt.message, err)
+			}
+			if strings.Contains(out, "hunter2") {
+				t.Errorf("unexpected output %q", out)
+			}
+		})
+	}
+	if fileExists(filepath.Join(dir, "plain.json.enc")) {
+		t.Error("expected nothing to be written for an invalid file")
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// serviceKey and serviceDirKey are the helper values a template in a service
+// directory sees: the service's name and the directory vibeops link puts it in
+const (
+	serviceKey    = "Service"
+	serviceDirKey = "ServiceDir"
+)
+
+// serviceDirOf returns the service directory of a relative path, e.g.
+// "its-the-vibe/Poppit/.env" -> "its-the-vibe/Poppit". Paths outside a service
+// directory return "".
+func serviceDirOf(relPath string) string {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return path.Join(parts[0], parts[1])
+}
+
+// withService returns a shallow copy of values with the name of serviceDir, a
+// build-relative service directory, bound to .Service and the directory it is
+// linked into, BaseDir joined with serviceDir, bound to .ServiceDir. .ServiceDir
+// is left unset when BaseDir is not a string.
+func withService(values map[string]interface{}, serviceDir string) map[string]interface{} {
+	bound := make(map[string]interface{}, len(values)+2)
+	for key, val := range values {
+		bound[key] = val
+	}
+	bound[serviceKey] = path.Base(serviceDir)
+	if baseDir, ok := values["BaseDir"].(string); ok {
+		bound[serviceDirKey] = filepath.Join(baseDir, filepath.FromSlash(serviceDir))
+	}
+	return bound
+}
+
+// serviceValueKeys records a service template's .ServiceDir references as a
+// dependency on BaseDir, which the directory is derived from, and drops its
+// .Service references, which come from the path alone
+func serviceValueKeys(keys []string) []string {
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		switch key {
+		case serviceKey:
+		case serviceDirKey:
+			result = append(result, "BaseDir")
+		default:
+			result = append(result, key)
+		}
+	}
+	return mergeValueKeys(result, nil)
+}
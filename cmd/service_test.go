@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestServiceDirOf(t *testing.T) {
+	tests := map[string]string{
+		"org/Poppit/.env":             "org/Poppit",
+		"org/Poppit/config/conf.yaml": "org/Poppit",
+		"org/README.md":               "",
+		"notes.txt":                   "",
+	}
+	for relPath, expected := range tests {
+		if got := serviceDirOf(relPath); got != expected {
+			t.Errorf("serviceDirOf(%q) = %q, want %q", relPath, got, expected)
+		}
+	}
+}
+
+func TestProcessTemplates_ServiceDir(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"__.OrgName__/Poppit/.env.tmpl":               "NAME={{ .Service }}\nDIR={{ .ServiceDir }}\n",
+		"__.OrgName__/__each.Projects__/dir.txt.tmpl": "{{ .ServiceDir }}",
+	})
+	values := map[string]interface{}{
+		"OrgName":  "org",
+		"BaseDir":  "/srv",
+		"Projects": []map[string]interface{}{{"name": "Alpha"}},
+	}
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{Strict: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"org/Poppit/.env":   "NAME=Poppit\nDIR=" + filepath.Join("/srv", "org", "Poppit") + "\n",
+		"org/Alpha/dir.txt": filepath.Join("/srv", "org", "Alpha"),
+	}
+	for relPath, content := range expected {
+		data, err := os.ReadFile(filepath.Join(buildDir, relPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("unexpected output for %s: %q", relPath, string(data))
+		}
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range manifest.Files {
+		if entry.Path == "org/Poppit/.env" && !reflect.DeepEqual(entry.ValueKeys, []string{"BaseDir"}) {
+			t.Errorf("expected .ServiceDir to record a dependency on BaseDir, got %v", entry.ValueKeys)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// stagingDirFor returns the sibling directory a build is rendered into before it is swapped into place
+func stagingDirFor(buildDir string) string {
+	return filepath.Clean(buildDir) + ".staging"
+}
+
+// defaultBackupDirFor returns the sibling directory the previous build is kept in by default
+func defaultBackupDirFor(buildDir string) string {
+	return filepath.Clean(buildDir) + ".bak"
+}
+
+// prepareStagingDir creates a fresh staging directory for buildDir, seeded with
+// the current build so that unchanged outputs keep their mtimes. Files are
+// hard-linked where possible; outputs are always replaced by rename, never
+// modified in place, so the live build is never affected by the staged run.
+func prepareStagingDir(buildDir string) (string, error) {
+	stagingDir := stagingDirFor(buildDir)
+
+	// Remove anything left behind by an interrupted run
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return "", fmt.Errorf("failed to remove stale staging directory %s: %w", stagingDir, err)
+	}
+
+	if _, err := os.Stat(buildDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(stagingDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create staging directory %s: %w", stagingDir, err)
+		}
+		return stagingDir, nil
+	}
+
+	if err := cloneTree(buildDir, stagingDir); err != nil {
+		os.RemoveAll(stagingDir)
+		return "", fmt.Errorf("failed to seed staging directory from %s: %w", buildDir, err)
+	}
+	return stagingDir, nil
+}
+
+// cloneTree recreates the directory tree at src in dst, hard-linking regular
+// files and falling back to a copy that preserves mode and mtime
+func cloneTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		target := filepath.Join(dst, relPath)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			// Build directories only contain regular files; ignore anything else
+			return nil
+		}
+
+		if err := os.Link(path, target); err == nil {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile copies a regular file, preserving its permissions and modification time
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// validateBuildTree checks that every JSON and YAML file in dir is well-formed,
+// including files carried over from the previous build
+func validateBuildTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		isJSON := strings.HasSuffix(path, ".json")
+		isYAML := strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+		if !isJSON && !isYAML {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s for validation: %w", path, err)
+		}
+		if isJSON {
+			return utils.ValidateJSON(data, path)
+		}
+		return utils.ValidateYAML(data, path)
+	})
+}
+
+// swapBuildDir moves stagingDir into place as buildDir. The previous build is
+// kept in backupDir, or removed when backupDir is empty. If the final rename
+// fails the previous build is restored.
+func swapBuildDir(stagingDir, buildDir, backupDir string) error {
+	if _, err := os.Stat(buildDir); os.IsNotExist(err) {
+		if err := os.Rename(stagingDir, buildDir); err != nil {
+			return fmt.Errorf("failed to move %s into place: %w", stagingDir, err)
+		}
+		return nil
+	}
+
+	// Without a backup, park the previous build next to it until the swap succeeds
+	keepBackup := backupDir != ""
+	if !keepBackup {
+		backupDir = filepath.Clean(buildDir) + ".old"
+	}
+
+	if err := os.RemoveAll(backupDir); err != nil {
+		return fmt.Errorf("failed to remove previous backup %s: %w", backupDir, err)
+	}
+	if err := os.Rename(buildDir, backupDir); err != nil {
+		return fmt.Errorf("failed to move previous build to %s: %w", backupDir, err)
+	}
+	if err := os.Rename(stagingDir, buildDir); err != nil {
+		if restoreErr := os.Rename(backupDir, buildDir); restoreErr != nil {
+			return fmt.Errorf("failed to move %s into place: %w (and failed to restore previous build from %s: %v)", stagingDir, err, backupDir, restoreErr)
+		}
+		return fmt.Errorf("failed to move %s into place: %w", stagingDir, err)
+	}
+
+	if !keepBackup {
+		if err := os.RemoveAll(backupDir); err != nil {
+			return fmt.Errorf("failed to remove previous build %s: %w", backupDir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProcessTemplates_AtomicSwapKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+	backupDir := filepath.Join(dir, "build.bak")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"svc/.env.tmpl":    "KEY={{.Key}}",
+		"svc/static.tmpl":  "static",
+		"svc/config.tmpl":  "{{.Key}}",
+		"other/value.tmpl": "{{.Other}}",
+	})
+
+	opts := templateOptions{Atomic: true, BackupDir: backupDir}
+	values := map[string]interface{}{"Key": "one", "Other": "x"}
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Backdate an unchanged output to check its mtime survives the swap
+	staticPath := filepath.Join(buildDir, "svc", "static")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(staticPath, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	values["Key"] = "two"
+	result, err := processTemplates([]string{sourceDir}, buildDir, values, opts)
+	if err != nil {
+		t.Fatalf("unexpected error on second run: %v", err)
+	}
+	if result.Summary() != "0 created, 2 updated, 2 unchanged" {
+		t.Errorf("unexpected summary: %s", result.Summary())
+	}
+	for _, out := range result.Outputs {
+		if filepath.Dir(filepath.Dir(out.Path)) != buildDir {
+			t.Errorf("expected output path inside %s, got %s", buildDir, out.Path)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(buildDir, "svc", ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "KEY=two" {
+		t.Errorf("expected new build to contain 'KEY=two', got %q", string(data))
+	}
+
+	data, err = os.ReadFile(filepath.Join(backupDir, "svc", ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "KEY=one" {
+		t.Errorf("expected backup to contain 'KEY=one', got %q", string(data))
+	}
+
+	info, err := os.Stat(staticPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("expected unchanged output to keep its mtime, got %v want %v", info.ModTime(), past)
+	}
+
+	if _, err := os.Stat(stagingDirFor(buildDir)); !os.IsNotExist(err) {
+		t.Errorf("expected staging directory to be gone, got err=%v", err)
+	}
+}
+
+func TestProcessTemplates_AtomicFailureLeavesBuildUntouched(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+	backupDir := filepath.Join(dir, "build.bak")
+
+	writeTestFiles(t, sourceDir, map[string]string{
+		"a/config.json.tmpl": `{"key": "{{.Key}}"}`,
+		"b/value.tmpl":       "{{.Key}}",
+	})
+
+	opts := templateOptions{Atomic: true, BackupDir: backupDir}
+	if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{"Key": "good"}, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Break the JSON template so the run fails after the other template was rendered
+	writeTestFiles(t, sourceDir, map[string]string{
+		"a/config.json.tmpl": `{"key": {{.Key}}}`,
+		"b/value.tmpl":       "changed {{.Key}}",
+	})
+	if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{"Key": "bad"}, opts); err == nil {
+		t.Fatal("expected error from invalid JSON template, got nil")
+	}
+
+	data, err := os.ReadFile(filepath.Join(buildDir, "b", "value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "good" {
+		t.Errorf("expected live build to be untouched, got %q", string(data))
+	}
+	if _, err := os.Stat(stagingDirFor(buildDir)); !os.IsNotExist(err) {
+		t.Errorf("expected staging directory to be removed, got err=%v", err)
+	}
+	if _, err := os.Stat(backupDir); !os.IsNotExist(err) {
+		t.Errorf("expected no backup to be made on failure, got err=%v", err)
+	}
+}
+
+func TestProcessTemplates_AtomicWithoutBackup(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+
+	writeTestFiles(t, sourceDir, map[string]string{"value.tmpl": "{{.Key}}"})
+
+	opts := templateOptions{Atomic: true}
+	for _, key := range []string{"one", "two"} {
+		if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{"Key": key}, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "source" && entry.Name() != "build" {
+			t.Errorf("unexpected leftover directory %s", entry.Name())
+		}
+	}
+}
+
+func TestValidateBuildTree(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"ok.json":    `{"a": 1}`,
+		"ok.yaml":    "a: 1\n",
+		"notes.txt":  "{not json",
+		"bad/x.json": `{"a": }`,
+	})
+
+	if err := validateBuildTree(dir); err == nil {
+		t.Error("expected validation error for invalid JSON, got nil")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "bad", "x.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateBuildTree(dir); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// missingKeyPattern matches the error text/template produces for an undefined
+// map key when executed with missingkey=error, e.g.
+// template: config.yaml.tmpl:6:12: executing "config.yaml.tmpl" at <.RedisHost>: map has no entry for key "RedisHost"
+// The file is the one the failing node was parsed from, which is the partial's
+// path for a node inside a {{define}} block.
+var missingKeyPattern = regexp.MustCompile(`template: (.+?):(\d+):\d+: executing "[^"]*" at <([^>]*)>: map has no entry for key "([^"]*)"`)
+
+// missingKey describes an undefined value key referenced by a template
+type missingKey struct {
+	// Template is the template being rendered, even when the key is missing in a partial it includes
+	Template string
+	// Line is the line of Template the key is missing on, or 0 when it is missing in Via
+	Line int
+	Expr string
+	Key  string
+	// Via locates a key missing outside Template itself, e.g. "source/_partials/redis.tmpl:11"
+	Via string
+}
+
+// String formats the key as a line of the strict mode report
+func (mk missingKey) String() string {
+	location := mk.Template
+	if mk.Line > 0 {
+		location = fmt.Sprintf("%s:%d", mk.Template, mk.Line)
+	}
+	if mk.Via != "" {
+		return fmt.Sprintf("%s: %s (at <%s>) (via %s)", location, mk.Key, mk.Expr, mk.Via)
+	}
+	return fmt.Sprintf("%s: %s (at <%s>)", location, mk.Key, mk.Expr)
+}
+
+// missingKeysError is returned when a template references one or more undefined keys in strict mode
+type missingKeysError struct {
+	Template string
+	Keys     []missingKey
+}
+
+func (e *missingKeysError) Error() string {
+	return fmt.Sprintf("%d missing value key(s) in %s", len(e.Keys), e.Template)
+}
+
+// parseMissingKey extracts the file, line, expression and key from a missing key
+// execution error, reported against srcPath. A key missing in the template named
+// name itself is located by its line; one missing in a partial by the partial's
+// path and line.
+func parseMissingKey(srcPath, name string, err error) (missingKey, bool) {
+	matches := missingKeyPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return missingKey{}, false
+	}
+	line, _ := strconv.Atoi(matches[2])
+	key := missingKey{Template: srcPath, Line: line, Expr: matches[3], Key: matches[4]}
+	if matches[1] != name {
+		key.Line, key.Via = 0, fmt.Sprintf("%s:%d", matches[1], line)
+	}
+	return key, true
+}
+
+// executeStrict executes tmpl with missingkey=error, collecting every undefined
+// key rather than stopping at the first one. Each time a key is reported missing
+// it is stubbed out where the expression looked for it, in nested maps too, and
+// the template is executed again. Nothing is written to w unless execution
+// succeeds without missing keys.
+func executeStrict(tmpl *template.Template, w io.Writer, srcPath string, values map[string]interface{}) error {
+	tmpl.Option("missingkey=error")
+
+	// Stubs are added to a copy so the caller's values, nested maps included, are left alone
+	stubbed, _ := copyValue(values).(map[string]interface{})
+
+	var missing []missingKey
+	attempts := make(map[missingKey]int)
+	for {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, stubbed)
+		if err == nil {
+			if len(missing) > 0 {
+				return &missingKeysError{Template: srcPath, Keys: missing}
+			}
+			_, err := w.Write(buf.Bytes())
+			return err
+		}
+
+		key, ok := parseMissingKey(srcPath, tmpl.Name(), err)
+		if !ok {
+			if len(missing) > 0 {
+				// Most likely a knock-on effect of a stubbed key, so report what we have
+				return &missingKeysError{Template: srcPath, Keys: missing}
+			}
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+
+		attempts[key]++
+		switch attempts[key] {
+		case 1:
+			missing = append(missing, key)
+			stubMissingKey(stubbed, key)
+		case 2:
+			// The expression is relative to a range or with block rather than the
+			// values, so stub the key in every nested map missing it
+			stubKeyEverywhere(stubbed, key.Key)
+		default:
+			return &missingKeysError{Template: srcPath, Keys: missing}
+		}
+	}
+}
+
+// stubMissingKey adds the key reported missing to the map the expression looked
+// it up in, e.g. Redis for <.Redis.Port>. The stub is an empty map when the
+// expression goes on to look up fields of the key, and an empty string otherwise.
+func stubMissingKey(values map[string]interface{}, key missingKey) {
+	fields := strings.Split(strings.TrimPrefix(strings.TrimPrefix(key.Expr, "$"), "."), ".")
+	m := values
+	for i, field := range fields {
+		if field == key.Key {
+			if _, exists := m[field]; !exists {
+				if i < len(fields)-1 {
+					m[field] = map[string]interface{}{}
+				} else {
+					m[field] = ""
+				}
+				return
+			}
+		}
+		next, ok := m[field].(map[string]interface{})
+		if !ok {
+			break
+		}
+		m = next
+	}
+	// The expression does not start from the values, e.g. it uses a variable
+	stubKeyEverywhere(values, key.Key)
+}
+
+// stubKeyEverywhere adds key as an empty string to every map in value missing it
+func stubKeyEverywhere(value interface{}, key string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, exists := v[key]; !exists {
+			v[key] = ""
+		}
+		for _, item := range v {
+			stubKeyEverywhere(item, key)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			stubKeyEverywhere(item, key)
+		}
+	case []interface{}:
+		for _, item := range v {
+			stubKeyEverywhere(item, key)
+		}
+	}
+}
+
+// copyValue returns a deep copy of the maps and lists in value
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = copyValue(item)
+		}
+		return m
+	case []map[string]interface{}:
+		list := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			list[i], _ = copyValue(item).(map[string]interface{})
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = copyValue(item)
+		}
+		return list
+	}
+	return value
+}
+
+// strictReport aggregates template failures collected while rendering in strict mode
+type strictReport struct {
+	MissingKeys []missingKey
+	Errors      []error
+}
+
+// Add records the error returned for a single template
+func (r *strictReport) Add(err error) {
+	var mkErr *missingKeysError
+	if errors.As(err, &mkErr) {
+		r.MissingKeys = append(r.MissingKeys, mkErr.Keys...)
+		return
+	}
+	r.Errors = append(r.Errors, err)
+}
+
+// Empty reports whether no failures were recorded
+func (r *strictReport) Empty() bool {
+	return len(r.MissingKeys) == 0 && len(r.Errors) == 0
+}
+
+// Print writes the aggregated report, grouped and sorted by template path
+func (r *strictReport) Print(w io.Writer) {
+	if len(r.MissingKeys) > 0 {
+		r.sortMissingKeys()
+		templates := make(map[string]bool)
+		for _, mk := range r.MissingKeys {
+			templates[mk.Template] = true
+		}
+		fmt.Fprintf(w, "\nStrict mode: %d missing value key(s) in %d template(s):\n", len(r.MissingKeys), len(templates))
+		for _, mk := range r.MissingKeys {
+			fmt.Fprintf(w, "  %s\n", mk)
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(w, "\nStrict mode: %d template(s) failed:\n", len(r.Errors))
+		for _, err := range r.Errors {
+			fmt.Fprintf(w, "  %v\n", err)
+		}
+	}
+}
+
+// sortMissingKeys sorts the missing keys by template and location, dropping
+// duplicates, e.g. from the instances of a fanned-out template
+func (r *strictReport) sortMissingKeys() {
+	sort.SliceStable(r.MissingKeys, func(i, j int) bool {
+		a, b := r.MissingKeys[i], r.MissingKeys[j]
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Via != b.Via {
+			return a.Via < b.Via
+		}
+		return a.Key+a.Expr < b.Key+b.Expr
+	})
+	unique := r.MissingKeys[:0]
+	for i, mk := range r.MissingKeys {
+		if i == 0 || mk != r.MissingKeys[i-1] {
+			unique = append(unique, mk)
+		}
+	}
+	r.MissingKeys = unique
+}
+
+// Err returns a summary error when any failures were recorded
+func (r *strictReport) Err() error {
+	r.sortMissingKeys()
+	switch {
+	case r.Empty():
+		return nil
+	case len(r.Errors) == 0:
+		return fmt.Errorf("strict mode: %d missing value key(s)", len(r.MissingKeys))
+	case len(r.MissingKeys) == 0:
+		return fmt.Errorf("strict mode: %d template(s) failed", len(r.Errors))
+	default:
+		return fmt.Errorf("strict mode: %d missing value key(s) and %d failed template(s)", len(r.MissingKeys), len(r.Errors))
+	}
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestExecuteStrict_CollectsEveryMissingKey(t *testing.T) {
+	tmpl := template.Must(template.New("config.yaml.tmpl").Funcs(templateFuncs()).Parse(
+		"host: {{.RedisHost}}\npassword: {{.RedisPassword}}\nlist: {{.SlackLinerList}}\n"))
+
+	var buf bytes.Buffer
+	err := executeStrict(tmpl, &buf, "src/config.yaml.tmpl", map[string]interface{}{"RedisPassword": "secret"})
+
+	var mkErr *missingKeysError
+	if !errors.As(err, &mkErr) {
+		t.Fatalf("expected missingKeysError, got %v", err)
+	}
+	if len(mkErr.Keys) != 2 {
+		t.Fatalf("expected 2 missing keys, got %+v", mkErr.Keys)
+	}
+	if mkErr.Keys[0].Key != "RedisHost" || mkErr.Keys[0].Line != 1 {
+		t.Errorf("unexpected first missing key: %+v", mkErr.Keys[0])
+	}
+	if mkErr.Keys[1].Key != "SlackLinerList" || mkErr.Keys[1].Line != 3 {
+		t.Errorf("unexpected second missing key: %+v", mkErr.Keys[1])
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing written on failure, got %q", buf.String())
+	}
+}
+
+func TestExecuteStrict_Success(t *testing.T) {
+	tmpl := template.Must(template.New("ok.tmpl").Parse("{{.Key}}"))
+
+	var buf bytes.Buffer
+	if err := executeStrict(tmpl, &buf, "ok.tmpl", map[string]interface{}{"Key": "value"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "value" {
+		t.Errorf("expected 'value', got %q", buf.String())
+	}
+}
+
+func TestExecuteStrict_NestedMissingKey(t *testing.T) {
+	tmpl := template.Must(template.New("nested.tmpl").Parse(
+		"{{range .Projects}}{{.portKey}}{{end}}\n{{.Redis.Port}}\n{{.Redis.Host}}\n{{.Cache.Addr}}\n"))
+
+	values := map[string]interface{}{
+		"Projects": []map[string]interface{}{{"name": "A"}, {"name": "B"}},
+		"Redis":    map[string]interface{}{"Host": "localhost"},
+	}
+	err := executeStrict(tmpl, &bytes.Buffer{}, "src/nested.tmpl", values)
+
+	var mkErr *missingKeysError
+	if !errors.As(err, &mkErr) {
+		t.Fatalf("expected missingKeysError, got %v", err)
+	}
+	expected := []missingKey{
+		{Template: "src/nested.tmpl", Line: 1, Expr: ".portKey", Key: "portKey"},
+		{Template: "src/nested.tmpl", Line: 2, Expr: ".Redis.Port", Key: "Port"},
+		{Template: "src/nested.tmpl", Line: 4, Expr: ".Cache.Addr", Key: "Cache"},
+		{Template: "src/nested.tmpl", Line: 4, Expr: ".Cache.Addr", Key: "Addr"},
+	}
+	if !reflect.DeepEqual(mkErr.Keys, expected) {
+		t.Errorf("missing keys = %+v, want %+v", mkErr.Keys, expected)
+	}
+	if _, ok := values["Redis"].(map[string]interface{})["Port"]; ok {
+		t.Error("expected the values not to be modified")
+	}
+	if _, ok := values["Projects"].([]map[string]interface{})[0]["portKey"]; ok {
+		t.Error("expected the projects not to be modified")
+	}
+}
+
+func TestExecuteStrict_MissingKeyInPartial(t *testing.T) {
+	partials := template.Must(template.New("source/_partials/env.tmpl").Parse("{{define \"env\"}}\nKEY={{.PartialKey}}\n{{end}}"))
+	tmpl := template.Must(template.Must(partials.Clone()).New("app.env.tmpl").Parse("{{template \"env\" .}}\n{{.OwnKey}}\n"))
+
+	err := executeStrict(tmpl, &bytes.Buffer{}, "source/app.env.tmpl", map[string]interface{}{})
+
+	var mkErr *missingKeysError
+	if !errors.As(err, &mkErr) {
+		t.Fatalf("expected missingKeysError, got %v", err)
+	}
+	expected := []missingKey{
+		{Template: "source/app.env.tmpl", Expr: ".PartialKey", Key: "PartialKey", Via: "source/_partials/env.tmpl:2"},
+		{Template: "source/app.env.tmpl", Line: 2, Expr: ".OwnKey", Key: "OwnKey"},
+	}
+	if !reflect.DeepEqual(mkErr.Keys, expected) {
+		t.Errorf("missing keys = %+v, want %+v", mkErr.Keys, expected)
+	}
+}
+
+func TestStrictReport_Print(t *testing.T) {
+	report := &strictReport{}
+	for _, srcPath := range []string{"source/b.tmpl", "source/a.tmpl", "source/b.tmpl"} {
+		report.Add(&missingKeysError{Template: srcPath, Keys: []missingKey{
+			{Template: srcPath, Expr: ".RedisHost", Key: "RedisHost", Via: "source/_partials/redis.tmpl:11"},
+			{Template: srcPath, Line: 3, Expr: ".Port", Key: "Port"},
+		}})
+	}
+
+	var out bytes.Buffer
+	report.Print(&out)
+	expected := "\nStrict mode: 4 missing value key(s) in 2 template(s):\n" +
+		"  source/a.tmpl: RedisHost (at <.RedisHost>) (via source/_partials/redis.tmpl:11)\n" +
+		"  source/a.tmpl:3: Port (at <.Port>)\n" +
+		"  source/b.tmpl: RedisHost (at <.RedisHost>) (via source/_partials/redis.tmpl:11)\n" +
+		"  source/b.tmpl:3: Port (at <.Port>)\n"
+	if out.String() != expected {
+		t.Errorf("Print() =\n%s\nwant\n%s", out.String(), expected)
+	}
+	if err := report.Err(); err == nil || err.Error() != "strict mode: 4 missing value key(s)" {
+		t.Errorf("Err() = %v", err)
+	}
+}
+
+func TestProcessTemplates_StrictAggregatesReport(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"a.txt.tmpl":  "{{.Missing1}}",
+		"b.txt.tmpl":  "{{.Present}} {{.Missing2}}",
+		"ok.txt.tmpl": "{{.Present}}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{"Present": "here"}, templateOptions{Strict: true})
+	if err == nil {
+		t.Fatal("expected strict mode error, got nil")
+	}
+	if !strings.Contains(err.Error(), "2 missing value key(s)") {
+		t.Errorf("expected error to report 2 missing keys, got %v", err)
+	}
+
+	// Templates with missing keys must not leave output files behind
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(buildDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected no output file for %s, got err=%v", name, err)
+		}
+	}
+
+	// Templates without missing keys are still rendered
+	data, err := os.ReadFile(filepath.Join(buildDir, "ok.txt"))
+	if err != nil {
+		t.Fatalf("expected ok.txt to be rendered: %v", err)
+	}
+	if string(data) != "here" {
+		t.Errorf("expected 'here', got %q", string(data))
+	}
+}
+
+func TestProcessTemplates_StrictReportsFrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	writeTestFiles(t, sourceDir, map[string]string{
+		"a.txt.tmpl": "---\nwhen: .Enabled\n---\nstatic",
+		"b.txt.tmpl": "---\npath: \"{{ .Dir }}/b.txt\"\n---\nstatic",
+		"c.txt.tmpl": "{{ .Missing }}",
+	})
+
+	_, err := processTemplates([]string{sourceDir}, filepath.Join(dir, "build"), map[string]interface{}{}, templateOptions{Strict: true})
+	if err == nil || err.Error() != "strict mode: 3 missing value key(s)" {
+		t.Errorf("expected every missing key to be reported, got %v", err)
+	}
+}
+
+func TestProcessTemplates_NonStrictRendersNoValue(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	buildDir := filepath.Join(dir, "build")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "a.txt.tmpl"), []byte("{{.Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(buildDir, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "<no value>" {
+		t.Errorf("expected '<no value>', got %q", string(data))
+	}
+}
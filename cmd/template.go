@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/its-the-vibe/VibeOps/internal/utils"
 	"github.com/spf13/cobra"
@@ -83,61 +87,131 @@ func NewTemplateCmd() *cobra.Command {
 		Short: "Process template files and generate configuration files",
 		Long:  `Process all .tmpl files in the source folder and generate output files in the build folder.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			buildDir, _ := cmd.Flags().GetString("build-dir")
-			sourceDir, _ := cmd.Flags().GetString("source-dir")
-			followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
-
-			// Load values from values.json
-			values, err := utils.LoadValuesFromFile("values.json")
+			env, err := resolveEnv(cmd)
 			if err != nil {
-				return fmt.Errorf("error loading values.json: %w", err)
+				return err
 			}
-
-			// Load projects as []map[string]interface{} for template use
-			projectsList, err := utils.LoadProjectsMap("projects.json")
-			if err != nil {
-				return fmt.Errorf("error loading projects.json: %w", err)
+			buildDir := envBuildDir(cmd, env)
+			valuesFiles, _ := cmd.Flags().GetStringArray("values-file")
+			sourceDirs, _ := cmd.Flags().GetStringSlice("source-dir")
+			followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+			strict, _ := cmd.Flags().GetBool("strict")
+			backupDir, _ := cmd.Flags().GetString("backup-dir")
+			noBackup, _ := cmd.Flags().GetBool("no-backup")
+			prune, _ := cmd.Flags().GetBool("prune")
+			pruneDryRun, _ := cmd.Flags().GetBool("prune-dry-run")
+			policyFile, _ := cmd.Flags().GetString("policy")
+			jobs, _ := cmd.Flags().GetInt("jobs")
+			watch, _ := cmd.Flags().GetBool("watch")
+			watchInterval, _ := cmd.Flags().GetDuration("watch-interval")
+
+			if backupDir == "" {
+				backupDir = defaultBackupDirFor(buildDir)
 			}
-			values["Projects"] = projectsList
-
-			// Load ports from ports.json (optional)
-			ports, err := utils.LoadValuesFromFile("ports.json")
-			if err != nil {
-				return fmt.Errorf("error loading ports.json: %w", err)
+			if noBackup {
+				backupDir = ""
 			}
 
-			// Merge ports into values
-			mergedValues := utils.MergeValues(values, ports)
+			// load reads the merged values and the output policy a render needs
+			load := func() (map[string]interface{}, templateOptions, error) {
+				overrides, err := loadValueOverrides(cmd)
+				if err != nil {
+					return nil, templateOptions{}, err
+				}
+				schema, err := loadValuesSchema()
+				if err != nil {
+					return nil, templateOptions{}, err
+				}
+				// Load the merged values from values.json, projects.json, ports.json, the profile, the secrets and the overrides
+				mergedValues, secret, err := loadTemplateValues(env, overrides, schema)
+				if err != nil {
+					return nil, templateOptions{}, err
+				}
 
-			// Load bootstrap config (optional)
-			bootstrapConfig, err := utils.LoadBootstrapConfig("bootstrap.json")
-			if err != nil {
-				// Bootstrap config is optional, silently skip if not found
-			} else if bootstrapConfig.GCPSecretName != "" {
-				// Load GCP secret if configured
-				ctx := context.Background()
-				gcpSecrets, err := utils.LoadGCPSecret(ctx, bootstrapConfig.GCPSecretName)
+				// Load the sensitive output policy (optional)
+				policyConfig, err := utils.LoadOutputPolicy(policyFile)
+				if err != nil {
+					return nil, templateOptions{}, fmt.Errorf("error loading %s: %w", policyFile, err)
+				}
+				if schema != nil {
+					// Keys the schema marks secret are protected like the policy's secretKeys
+					policyConfig.SecretKeys = append(policyConfig.SecretKeys, schema.SecretKeys()...)
+				}
+				policy, err := newOutputPolicy(policyConfig)
 				if err != nil {
-					return fmt.Errorf("error loading GCP secret: %w", err)
+					return nil, templateOptions{}, fmt.Errorf("error loading %s: %w", policyFile, err)
 				}
-				fmt.Printf("Loaded %d values from GCP Secret Manager\n", len(gcpSecrets))
-				// Merge GCP secrets into values (GCP secrets override local values)
-				mergedValues = utils.MergeValues(mergedValues, gcpSecrets)
+
+				opts := templateOptions{
+					FollowSymlinks: followSymlinks,
+					Strict:         strict,
+					Atomic:         true,
+					BackupDir:      backupDir,
+					Secret:         secret,
+					Prune:          prune && !pruneDryRun,
+					PruneDryRun:    pruneDryRun,
+					Policy:         policy,
+					Jobs:           jobs,
+				}
+				return mergedValues, opts, nil
 			}
 
-			// Process templates
-			if err := processTemplates(sourceDir, buildDir, mergedValues, followSymlinks); err != nil {
-				return fmt.Errorf("error processing templates: %w", err)
+			render := func(values map[string]interface{}, opts templateOptions) error {
+				result, err := processTemplates(sourceDirs, buildDir, values, opts)
+				if err != nil {
+					return fmt.Errorf("error processing templates: %w", err)
+				}
+
+				fmt.Printf("Templates processed successfully! (%s)\n", result.Summary())
+				return nil
 			}
 
-			fmt.Println("Templates processed successfully!")
-			return nil
+			if watch {
+				// Stop watching cleanly on Ctrl+C
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer stop()
+				valueFiles := []string{"bootstrap.json", policyFile, valuesSchemaFile}
+				for _, name := range []string{"values", "ports", "projects"} {
+					valueFiles = append(valueFiles, utils.ValuesFileCandidates(name)...)
+				}
+				if env != "" {
+					valueFiles = append(valueFiles, envValueFileCandidates(env)...)
+				}
+				valueFiles = append(valueFiles, valuesFiles...)
+				watcher := &templateWatcher{
+					SourceDirs:     sourceDirs,
+					FollowSymlinks: followSymlinks,
+					ValueFiles:     valueFiles,
+					Interval:       watchInterval,
+					Debounce:       watchDebounce,
+					Load:           load,
+					Render:         render,
+				}
+				return watcher.Run(ctx)
+			}
+
+			values, opts, err := load()
+			if err != nil {
+				return err
+			}
+			return render(values, opts)
 		},
 	}
 
-	cmd.Flags().StringP("build-dir", "b", "build", "Output build directory")
-	cmd.Flags().StringP("source-dir", "s", "source", "Source directory containing template files")
+	cmd.Flags().StringP("build-dir", "b", "build", "Output build directory; build-<env> when --env is set")
+	cmd.Flags().StringSliceP("source-dir", "s", []string{"source"}, "Source directory containing template files; repeat to layer directories, later ones taking precedence")
 	cmd.Flags().Bool("follow-symlinks", false, "Follow symlinks in the source directory when processing templates")
+	cmd.Flags().Bool("strict", false, "Fail on value keys missing from the merged values, reporting every missing key")
+	cmd.Flags().String("backup-dir", "", "Directory to keep the previous build in after a successful run (default \"<build-dir>.bak\")")
+	cmd.Flags().Bool("no-backup", false, "Discard the previous build instead of keeping a backup")
+	cmd.Flags().Bool("prune", false, "Delete build files that no template in any source layer generated")
+	cmd.Flags().Bool("prune-dry-run", false, "List the stale files --prune would delete without deleting them; the templates are still rendered")
+	cmd.Flags().IntP("jobs", "j", 0, "Number of templates to render concurrently (default: one per CPU)")
+	cmd.Flags().String("policy", "output-policy.json", "Sensitive output policy file (defaults to protecting .env and .secret files when missing)")
+	cmd.Flags().Bool("watch", false, "Keep running and re-render whenever the source directories or values files change")
+	cmd.Flags().Duration("watch-interval", 500*time.Millisecond, "How often --watch polls for changes")
+	addEnvFlag(cmd)
+	addValueOverrideFlags(cmd)
 	return cmd
 }
 
@@ -153,129 +227,374 @@ func expandPathVars(path string, values map[string]interface{}) string {
 	return path
 }
 
-// processTemplates walks through the source directory and processes .tmpl files
-func processTemplates(sourceDir, buildDir string, values map[string]interface{}, followSymlinks bool) error {
-	// Create build directory if it doesn't exist
-	if err := os.MkdirAll(buildDir, 0755); err != nil {
-		return fmt.Errorf("failed to create build directory: %w", err)
+// templateOptions controls how templates are discovered and rendered
+type templateOptions struct {
+	// FollowSymlinks traverses symlinked directories in the source directory
+	FollowSymlinks bool
+	// Strict fails on any value key missing from the merged values, collecting
+	// every failure across the source tree before returning
+	Strict bool
+	// Atomic renders into a sibling staging directory and only swaps it into
+	// place as the build directory once every template has succeeded
+	Atomic bool
+	// BackupDir keeps the previous build after an atomic swap; empty discards it
+	BackupDir string
+	// Secret identifies the secret values were loaded from, for the build manifest
+	Secret *secretProvenance
+	// Prune deletes build files that no template in the rendered layers
+	// generated; PruneDryRun only lists them
+	Prune       bool
+	PruneDryRun bool
+	// Jobs is the number of templates rendered concurrently; 0 uses one per CPU
+	Jobs int
+	// Quiet skips the line printed for each created or updated output
+	Quiet bool
+	// Policy decides which outputs are sensitive; nil uses the default policy
+	Policy *outputPolicy
+	// SecretValues maps secret value keys to their values, to detect secrets in
+	// outputs the policy does not cover. processTemplates fills it in.
+	SecretValues map[string]string
+	// Only restricts rendering to the templates at these source paths, leaving every
+	// other output and its manifest entry as it is, and disables pruning except for
+	// the previous outputs of these templates; nil renders every template
+	Only map[string]bool
+}
+
+// processTemplates renders the .tmpl files of every source layer into the build
+// directory, later layers taking precedence, and returns a record of every file generated
+func processTemplates(sourceDirs []string, buildDir string, values map[string]interface{}, opts templateOptions) (result *buildResult, err error) {
+	// Look for the values of keys marked secret, and of keys loaded from the secret, in every output
+	if opts.Policy == nil {
+		if opts.Policy, err = newOutputPolicy(utils.DefaultOutputPolicy()); err != nil {
+			return nil, err
+		}
+	}
+	var secretKeys []string
+	if opts.Secret != nil {
+		for key := range opts.Secret.Keys {
+			secretKeys = append(secretKeys, key)
+		}
+	}
+	opts.SecretValues = collectSecretValues(values, opts.Policy.SecretKeys, secretKeys)
+
+	// Parse shared partials once so every template can use their {{define}} blocks
+	partials, err := loadPartials(sourceDirs, opts.FollowSymlinks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load partials: %w", err)
+	}
+
+	// In atomic mode, render into a staging directory so the live build is untouched until the swap
+	outputDir := buildDir
+	if opts.Atomic {
+		if outputDir, err = prepareStagingDir(buildDir); err != nil {
+			return nil, err
+		}
+		// Discard the staging directory if anything fails before the swap
+		defer func() {
+			if err != nil {
+				os.RemoveAll(outputDir)
+			}
+		}()
+	} else if err := os.MkdirAll(buildDir, 0755); err != nil {
+		// Create build directory if it doesn't exist
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+
+	result, err = renderSourceTree(sourceDirs, outputDir, buildDir, values, partials, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	walkFn := func(path string, d fs.DirEntry, err error) error {
+	// The manifest from the previous run tells us which files belong to other layers
+	previous, err := loadManifest(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove outputs no template generated, or only list them in a dry run
+	if (opts.Prune || opts.PruneDryRun) && opts.Only == nil {
+		stale, err := findStaleOutputs(outputDir, sourceDirs, result, previous)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to find stale outputs: %w", err)
 		}
+		for _, relPath := range stale {
+			if opts.PruneDryRun {
+				fmt.Printf("[DRY RUN] Would prune: %s\n", filepath.Join(buildDir, relPath))
+			} else {
+				fmt.Printf("Pruned: %s\n", filepath.Join(buildDir, relPath))
+			}
+		}
+		if !opts.PruneDryRun {
+			if err := pruneOutputs(outputDir, stale); err != nil {
+				return nil, err
+			}
+			result.Pruned = stale
+		}
+	}
 
-		// Skip the source directory itself
-		if path == sourceDir {
-			return nil
+	// A re-rendered template whose output moved, e.g. after a front-matter path
+	// change, leaves its previous output behind, so remove it
+	if opts.Only != nil {
+		moved := movedOutputs(previous, opts.Only, result)
+		for _, relPath := range moved {
+			fmt.Printf("Pruned: %s\n", filepath.Join(buildDir, relPath))
+		}
+		if err := pruneOutputs(outputDir, moved); err != nil {
+			return nil, err
 		}
+		result.Pruned = moved
+	}
 
-		// Calculate relative path from source directory
-		relPath, err := filepath.Rel(sourceDir, path)
-		if err != nil {
-			return fmt.Errorf("failed to get relative path: %w", err)
+	// Record where every output came from alongside the outputs themselves. After
+	// rendering only some templates, every other entry is kept as it was.
+	renderedLayers := sourceDirs
+	if opts.Only != nil {
+		renderedLayers = nil
+	}
+	if err := updateManifest(outputDir, renderedLayers, result, opts.Secret); err != nil {
+		return nil, err
+	}
+
+	if opts.Atomic {
+		if err := validateBuildTree(outputDir); err != nil {
+			return nil, fmt.Errorf("staged build failed validation, leaving %s untouched: %w", buildDir, err)
+		}
+		if err := swapBuildDir(outputDir, buildDir, opts.BackupDir); err != nil {
+			return nil, err
 		}
+		for i := range result.Outputs {
+			result.Outputs[i].Path = filepath.Join(buildDir, result.Outputs[i].RelPath)
+		}
+	}
 
-		// Expand __.Key__ placeholders in the relative path
-		expandedRelPath := expandPathVars(relPath, values)
+	return result, nil
+}
 
-		// If it's a directory, create it in the build folder
-		if d.IsDir() {
-			buildPath := filepath.Join(buildDir, expandedRelPath)
-			if err := os.MkdirAll(buildPath, 0755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", buildPath, err)
-			}
-			return nil
+// renderSourceTree renders every template in the source layers into outputDir.
+// Paths are reported relative to buildDir, the final location of the build.
+func renderSourceTree(sourceDirs []string, outputDir, buildDir string, values map[string]interface{}, partials *template.Template, opts templateOptions) (*buildResult, error) {
+	// Discover every template first, then render them concurrently
+	report := &strictReport{}
+	sources, dirs, err := collectTemplates(sourceDirs, values, opts, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decide which layer's template generates each output before writing anything
+	sources, err = resolveLayers(sources)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Only != nil {
+		sources = onlySources(sources, opts.Only)
+	}
+
+	// Mirror the source directory structure in the build folder
+	for _, dir := range dirs {
+		buildPath := filepath.Join(outputDir, dir)
+		if err := os.MkdirAll(buildPath, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", buildPath, err)
 		}
+	}
 
-		// Only process .tmpl files
-		if !strings.HasSuffix(path, ".tmpl") {
-			return nil
+	outcomes := renderInParallel(sources, outputDir, values, partials, opts)
+
+	// Report in discovery order so output and errors are the same for any number of jobs
+	result := &buildResult{}
+	var errs []error
+	for i, outcome := range outcomes {
+		src := sources[i]
+		if outcome.Err != nil {
+			err := fmt.Errorf("failed to process template %s: %w", src.Path, outcome.Err)
+			if opts.Strict {
+				// In strict mode, template failures are collected and reported together
+				report.Add(err)
+			} else {
+				errs = append(errs, err)
+			}
+			continue
 		}
 
-		// Process the template file
-		var outputFile string
-		if outputFile, err = processTemplateFile(path, buildDir, expandedRelPath, values); err != nil {
-			return fmt.Errorf("failed to process template %s: %w", path, err)
+		out := outcome.Output
+		for _, warning := range out.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		result.Add(out)
+		if out.Status != outputUnchanged && !opts.Quiet {
+			fmt.Printf("Processed (%s): %s\n", out.Status, filepath.Join(buildDir, out.RelPath))
 		}
+	}
 
-		fmt.Printf("Processed: %s\n", outputFile)
-		return nil
+	if !report.Empty() {
+		report.Print(os.Stderr)
+		return nil, report.Err()
 	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
 
-	// Walk through the source directory, optionally following symlinks
-	if followSymlinks {
-		return walkDirFollowSymlinks(sourceDir, walkFn)
+// onlySources keeps the sources whose template path is in only
+func onlySources(sources []templateSource, only map[string]bool) []templateSource {
+	kept := make([]templateSource, 0, len(only))
+	for _, src := range sources {
+		if only[src.Path] {
+			kept = append(kept, src)
+		}
 	}
-	return filepath.WalkDir(sourceDir, walkFn)
+	return kept
 }
 
-// processTemplateFile reads a template file, applies values, and writes the output
-func processTemplateFile(srcPath, buildDir, relPath string, values map[string]interface{}) (string, error) {
-	// Read the template file
-	tmplContent, err := os.ReadFile(srcPath)
+// renderedTemplate is the in-memory result of executing a template
+type renderedTemplate struct {
+	Content     []byte
+	ValueKeys   []string
+	FrontMatter frontMatter
+}
+
+// parsedTemplate is a template parsed once and ready to be executed for each of its outputs
+type parsedTemplate struct {
+	Path        string
+	Tmpl        *template.Template
+	FrontMatter frontMatter
+}
+
+// parseTemplate strips the front matter from a template's content and parses it.
+// When partials is non-nil its {{define}} blocks are available to the template.
+func parseTemplate(srcPath string, content []byte, partials *template.Template) (*parsedTemplate, error) {
+	// Front matter configures the output and is not part of the template itself
+	fm, body, err := splitFrontMatter(content)
 	if err != nil {
-		return "", fmt.Errorf("failed to read template file: %w", err)
+		return nil, err
+	}
+
+	// Start from a copy of the shared partials so templates cannot affect each other
+	var tmpl *template.Template
+	if partials != nil {
+		base, err := partials.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone partials: %w", err)
+		}
+		tmpl = base.New(filepath.Base(srcPath))
+	} else {
+		tmpl = template.New(filepath.Base(srcPath)).Funcs(templateFuncs())
 	}
 
 	// Parse the template
-	tmpl, err := template.New(filepath.Base(srcPath)).Parse(string(tmplContent))
+	tmpl, err = tmpl.Parse(string(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
+	return &parsedTemplate{Path: srcPath, Tmpl: tmpl, FrontMatter: fm}, nil
+}
 
-	// Remove .tmpl extension from the output filename
-	outputRelPath := strings.TrimSuffix(relPath, ".tmpl")
-	outputPath := filepath.Join(buildDir, outputRelPath)
+// execute runs a parsed template into memory. It is not safe to execute the same
+// parsedTemplate from several goroutines in strict mode.
+func (p *parsedTemplate) execute(values map[string]interface{}, opts templateOptions) (*renderedTemplate, error) {
+	// Execute the template into a buffer so a failure never leaves a partial file behind
+	var buf bytes.Buffer
+	if opts.Strict {
+		if err := executeStrict(p.Tmpl, &buf, p.Path, values); err != nil {
+			return nil, err
+		}
+	} else if err := p.Tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
 
-	// Create parent directories if needed
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create output directory: %w", err)
+	return &renderedTemplate{Content: buf.Bytes(), ValueKeys: p.valueKeys(), FrontMatter: p.FrontMatter}, nil
+}
+
+// valueKeys returns the value keys the template body and its front matter reference
+func (p *parsedTemplate) valueKeys() []string {
+	valueKeys := templateValueKeys(p.Tmpl)
+	if extra := p.FrontMatter.valueKeys(); len(extra) > 0 {
+		valueKeys = mergeValueKeys(valueKeys, extra)
 	}
+	return valueKeys
+}
 
-	// Create the output file
-	outputFile, err := os.Create(outputPath)
+// renderTemplateFile reads and parses a template file and executes it into memory.
+// When partials is non-nil its {{define}} blocks are available to the template.
+func renderTemplateFile(srcPath string, values map[string]interface{}, partials *template.Template, opts templateOptions) (*renderedTemplate, error) {
+	// Read the template file
+	tmplContent, err := os.ReadFile(srcPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to create output file: %w", err)
+		return nil, fmt.Errorf("failed to read template file: %w", err)
 	}
 
-	// Execute the template and write to output file
-	if err := tmpl.Execute(outputFile, values); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	parsed, err := parseTemplate(srcPath, tmplContent, partials)
+	if err != nil {
+		return nil, err
 	}
+	return parsed.execute(values, opts)
+}
 
-	// Close the file before validation to ensure all data is flushed
-	outputFile.Close()
-
-	// Set .env and .secret files to read-only for owner (0400) to protect sensitive data
-	if strings.HasSuffix(outputPath, ".env") || strings.HasSuffix(outputPath, ".secret") {
-		if err := os.Chmod(outputPath, 0400); err != nil {
-			return "", fmt.Errorf("failed to set permissions on sensitive file: %w", err)
-		}
+// processTemplateFile renders a template file, validates the result, and writes the output
+// if it differs from what is already in the build directory
+func processTemplateFile(srcPath, buildDir, relPath string, values map[string]interface{}, partials *template.Template, opts templateOptions) (outputFile, error) {
+	rendered, err := renderTemplateFile(srcPath, values, partials, opts)
+	if err != nil {
+		return outputFile{}, err
 	}
+	return writeRenderedOutput(rendered, srcPath, buildDir, relPath, opts)
+}
+
+// writeRenderedOutput validates a rendered template and writes it to relPath in
+// buildDir if it differs from what is already there
+func writeRenderedOutput(rendered *renderedTemplate, srcPath, buildDir, relPath string, opts templateOptions) (outputFile, error) {
+	content := rendered.Content
+
+	// Remove .tmpl extension from the output filename
+	outputRelPath := strings.TrimSuffix(relPath, ".tmpl")
+	outputPath := filepath.Join(buildDir, outputRelPath)
 
-	// Validate JSON files after generation
+	// Validate JSON files before writing
 	if strings.HasSuffix(outputPath, ".json") {
-		data, err := os.ReadFile(outputPath)
-		if err != nil {
-			return "", fmt.Errorf("failed to read generated file for validation: %w", err)
-		}
-		if err := utils.ValidateJSON(data, outputPath); err != nil {
-			return "", fmt.Errorf("generated invalid JSON: %w", err)
+		if err := utils.ValidateJSON(content, outputPath); err != nil {
+			return outputFile{}, fmt.Errorf("generated invalid JSON: %w", err)
 		}
 	}
 
-	// Validate YAML files after generation
+	// Validate YAML files before writing
 	if strings.HasSuffix(outputPath, ".yaml") || strings.HasSuffix(outputPath, ".yml") {
-		data, err := os.ReadFile(outputPath)
-		if err != nil {
-			return "", fmt.Errorf("failed to read generated file for validation: %w", err)
-		}
-		if err := utils.ValidateYAML(data, outputPath); err != nil {
-			return "", fmt.Errorf("generated invalid YAML: %w", err)
+		if err := utils.ValidateYAML(content, outputPath); err != nil {
+			return outputFile{}, fmt.Errorf("generated invalid YAML: %w", err)
 		}
 	}
 
-	return outputPath, nil
+	// Create parent directories if needed
+	outputDir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return outputFile{}, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Protect sensitive outputs (by default .env and .secret files) according to the output policy
+	perms, err := outputPermissions(outputRelPath, content, rendered.FrontMatter, opts)
+	if err != nil {
+		return outputFile{}, err
+	}
+
+	// Only rewrite the output file when its content, mode or ownership has changed
+	status, err := writeOwnedOutputFile(outputPath, content, perms.Mode, perms.Owner)
+	if err != nil {
+		return outputFile{}, err
+	}
+
+	var warnings []string
+	if perms.Warning != "" {
+		warnings = append(warnings, perms.Warning)
+	}
+
+	return outputFile{
+		Path:      outputPath,
+		RelPath:   outputRelPath,
+		Source:    srcPath,
+		Status:    status,
+		Hash:      contentHash(content),
+		Mode:      perms.Mode,
+		Sensitive: perms.Sensitive,
+		ValueKeys: rendered.ValueKeys,
+		Warnings:  warnings,
+	}, nil
 }
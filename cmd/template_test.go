@@ -47,12 +47,12 @@ func TestProcessTemplateFile_Basic(t *testing.T) {
 	}
 
 	values := map[string]interface{}{"Name": "World"}
-	outPath, err := processTemplateFile(srcPath, buildDir, "hello.tmpl", values)
+	out, err := processTemplateFile(srcPath, buildDir, "hello.tmpl", values, nil, templateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	data, err := os.ReadFile(outPath)
+	data, err := os.ReadFile(out.Path)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -73,16 +73,16 @@ func TestProcessTemplateFile_RemovesTmplExtension(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	outPath, err := processTemplateFile(srcPath, buildDir, "config.yaml.tmpl", map[string]interface{}{})
+	out, err := processTemplateFile(srcPath, buildDir, "config.yaml.tmpl", map[string]interface{}{}, nil, templateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if strings.HasSuffix(outPath, ".tmpl") {
-		t.Errorf("output path should not have .tmpl extension, got %q", outPath)
+	if strings.HasSuffix(out.Path, ".tmpl") {
+		t.Errorf("output path should not have .tmpl extension, got %q", out.Path)
 	}
-	if !strings.HasSuffix(outPath, "config.yaml") {
-		t.Errorf("output path should end with config.yaml, got %q", outPath)
+	if !strings.HasSuffix(out.Path, "config.yaml") {
+		t.Errorf("output path should end with config.yaml, got %q", out.Path)
 	}
 }
 
@@ -98,7 +98,7 @@ func TestProcessTemplateFile_InvalidTemplate(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err := processTemplateFile(srcPath, buildDir, "bad.tmpl", map[string]interface{}{})
+	_, err := processTemplateFile(srcPath, buildDir, "bad.tmpl", map[string]interface{}{}, nil, templateOptions{})
 	if err == nil {
 		t.Error("expected error for invalid template, got nil")
 	}
@@ -119,7 +119,7 @@ func TestProcessTemplates_Basic(t *testing.T) {
 	}
 
 	values := map[string]interface{}{"Key": "testval"}
-	if err := processTemplates(sourceDir, buildDir, values, false); err != nil {
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -146,7 +146,7 @@ func TestProcessTemplates_ExpandsPathVars(t *testing.T) {
 	}
 
 	values := map[string]interface{}{"OrgName": "myorg"}
-	if err := processTemplates(sourceDir, buildDir, values, false); err != nil {
+	if _, err := processTemplates([]string{sourceDir}, buildDir, values, templateOptions{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -171,12 +171,12 @@ func TestProcessTemplateFile_EnvFilePermissions(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	outPath, err := processTemplateFile(srcPath, buildDir, ".env.tmpl", map[string]interface{}{})
+	out, err := processTemplateFile(srcPath, buildDir, ".env.tmpl", map[string]interface{}{}, nil, templateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	info, err := os.Stat(outPath)
+	info, err := os.Stat(out.Path)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/its-the-vibe/VibeOps/internal/utils"
 	"github.com/spf13/cobra"
@@ -13,32 +15,64 @@ func NewValidateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "validate",
 		Short: "Validate all JSON configuration files",
-		Long:  `Validate that all JSON configuration files (values.json, ports.json, projects.json, config.json) are valid and well-formed.`,
+		Long: `Validate that all configuration files (values, ports and projects in JSON, YAML or TOML, and config.json) are valid and well-formed,
+then cross-reference the values with the templates: keys no template uses are listed per file and
+keys templates use that no file defines are reported per template, failing validation.
+Every environment profile (values.<env>, ports.<env> and bootstrap.<env>.json) is checked
+the same way, or only the one given with --env.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceDirs, _ := cmd.Flags().GetStringSlice("source-dir")
+			followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+			skipCrossReference, _ := cmd.Flags().GetBool("skip-cross-reference")
+			env, err := resolveEnv(cmd)
+			if err != nil {
+				return err
+			}
 			hasErrors := false
 
-			// Validate values.json
-			if err := validateFile("values.json", true); err != nil {
+			// Validate values.json, which may also be values.yaml, values.yml or values.toml
+			valuesFile, err := utils.FindValuesFile("values")
+			if err == nil {
+				err = validateFile(valuesFile, true)
+			}
+			if err != nil {
 				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
 				hasErrors = true
 			} else {
-				fmt.Println("✓ values.json is valid")
+				fmt.Printf("✓ %s is valid\n", valuesFile)
 			}
 
 			// Validate ports.json (optional)
-			if err := validateFile("ports.json", false); err != nil {
+			portsFile, err := utils.FindValuesFile("ports")
+			if err == nil {
+				err = validateFile(portsFile, false)
+			}
+			if err != nil {
 				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
 				hasErrors = true
 			} else {
-				printOptionalFileStatus("ports.json")
+				printOptionalFileStatus(portsFile)
 			}
 
 			// Validate projects.json
-			if err := validateFile("projects.json", true); err != nil {
+			projectsFile, err := utils.FindValuesFile("projects")
+			if err == nil {
+				err = validateFile(projectsFile, true)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+				hasErrors = true
+			} else {
+				fmt.Printf("✓ %s is valid\n", projectsFile)
+			}
+
+			// Validate values.schema.json (optional)
+			schema, err := loadValuesSchema()
+			if err != nil {
 				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
 				hasErrors = true
 			} else {
-				fmt.Println("✓ projects.json is valid")
+				printOptionalFileStatus(valuesSchemaFile)
 			}
 
 			// Validate config.json (optional)
@@ -49,18 +83,238 @@ func NewValidateCmd() *cobra.Command {
 				printOptionalFileStatus("config.json")
 			}
 
+			// Validate bootstrap.json and its secret providers (optional)
+			if err := validateFile("bootstrap.json", false); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+				hasErrors = true
+			} else {
+				printOptionalFileStatus("bootstrap.json")
+			}
+
+			// Check the given profile, or the base files alone and every profile found
+			profiles := []string{env}
+			if env == "" {
+				discovered, err := discoverEnvs()
+				if err != nil {
+					return err
+				}
+				profiles = append(profiles, discovered...)
+			}
+			for _, profile := range profiles {
+				if profile != "" && validateEnvFiles(profile) {
+					hasErrors = true
+				}
+			}
+
 			if hasErrors {
 				return fmt.Errorf("validation failed for one or more JSON files")
 			}
 
 			fmt.Println("\n✓ All JSON files are valid!")
-			return nil
+
+			// Load every profile's values once, so each secret provider runs once
+			sets, err := loadProfileValues(profiles)
+			if err != nil {
+				return err
+			}
+			valuesErr := validateMergedValues(schema, sets)
+			if skipCrossReference {
+				return valuesErr
+			}
+			if err := validateValueReferences(sourceDirs, followSymlinks, sets, schema); err != nil {
+				return err
+			}
+			return valuesErr
 		},
 	}
 
+	cmd.Flags().StringSliceP("source-dir", "s", []string{"source"}, "Source directories whose templates are cross-referenced with the values")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symlinks in the source directories")
+	cmd.Flags().Bool("skip-cross-reference", false, "Only validate the JSON files, without checking for unused and undefined value keys")
+	addEnvFlag(cmd)
 	return cmd
 }
 
+// profileValues is the value sources of one profile ("" for the base files alone)
+type profileValues struct {
+	Env string
+	Set *valueSet
+}
+
+// loadProfileValues loads the value sources of each profile
+func loadProfileValues(envs []string) ([]profileValues, error) {
+	sets := make([]profileValues, len(envs))
+	for i, env := range envs {
+		set, err := loadValueSources(env)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = profileValues{Env: env, Set: set}
+	}
+	return sets, nil
+}
+
+// validateMergedValues merges the values of each profile, reporting type
+// conflicts between the sources when they are deep merged, resolves the ${Key}
+// references and checks the result against the values schema, after applying
+// its defaults. Without a schema only the merge and the references are checked,
+// and only failures are reported.
+func validateMergedValues(schema *utils.ValuesSchema, sets []profileValues) error {
+	if schema != nil {
+		fmt.Printf("\nChecking values against %s...\n", valuesSchemaFile)
+	}
+	unresolved, conflicts, failed := 0, 0, 0
+	for _, profile := range sets {
+		label := "Base values"
+		if profile.Env != "" {
+			label = "Values of profile " + profile.Env
+		}
+		set := profile.Set
+		merged, err := mergeValueSources(set.Sources, set.Merge)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", label, err)
+			conflicts++
+			continue
+		}
+		if schema != nil {
+			merged = schema.ApplyDefaults(merged)
+		}
+		resolved, err := utils.InterpolateValues(merged, literalPaths(set.Sources))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", label, err)
+			unresolved++
+			continue
+		}
+		if schema == nil {
+			continue
+		}
+
+		violations := schema.Validate(resolved)
+		if len(violations) == 0 {
+			fmt.Printf("✓ %s match the schema\n", label)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "❌ %s do not match the schema:\n", label)
+		for _, violation := range violations {
+			fmt.Fprintf(os.Stderr, "    %s\n", violation)
+		}
+		failed++
+	}
+
+	switch {
+	case conflicts > 0:
+		return fmt.Errorf("values that cannot be merged")
+	case unresolved > 0 && failed > 0:
+		return fmt.Errorf("values with unresolved references that do not match %s", valuesSchemaFile)
+	case unresolved > 0:
+		return fmt.Errorf("values with unresolved references")
+	case failed > 0:
+		return fmt.Errorf("values do not match %s", valuesSchemaFile)
+	}
+	return nil
+}
+
+// validateValueReferences cross-references the values of each profile with the
+// keys the templates reference, reporting unused keys and failing on undefined
+// keys. Keys with a default in the schema count as defined.
+func validateValueReferences(sourceDirs []string, followSymlinks bool, sets []profileValues, schema *utils.ValuesSchema) error {
+	var existing []string
+	for _, sourceDir := range sourceDirs {
+		if fileExists(sourceDir) {
+			existing = append(existing, sourceDir)
+		} else {
+			fmt.Printf("ℹ %s not found, not cross-referencing its templates\n", sourceDir)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\nCross-referencing values with the templates in %s...\n", strings.Join(existing, ", "))
+	deps, err := analyzeTemplateDeps(existing, followSymlinks)
+	if err != nil {
+		return fmt.Errorf("error analysing templates: %w", err)
+	}
+
+	undefined := 0
+	checkedBase := len(sets) > 0 && sets[0].Env == ""
+	for _, profile := range sets {
+		env, set := profile.Env, profile.Set
+		if env != "" {
+			fmt.Printf("\nProfile %s:\n", env)
+		}
+
+		sources := set.Sources
+		if schema != nil {
+			sources = append([]valueSource{{Name: valuesSchemaFile + " defaults", Values: schema.Defaults()}}, sources...)
+		}
+		xref := crossReferenceValues(sources, deps)
+		if env != "" && checkedBase {
+			// The base files' unused keys were already listed
+			xref.Unused = envUnusedKeys(xref.Unused, env)
+		}
+		xref.printUnused(os.Stdout)
+		if len(xref.Undefined) > 0 {
+			xref.printUndefined(os.Stderr)
+			undefined += len(xref.Undefined)
+			continue
+		}
+		fmt.Println("✓ Every key the templates reference is defined")
+	}
+
+	if undefined > 0 {
+		return fmt.Errorf("%d undefined value key reference(s) in templates", undefined)
+	}
+	return nil
+}
+
+// envUnusedKeys keeps the unused keys of the profile's own values and ports files
+func envUnusedKeys(unused []sourceKeys, env string) []sourceKeys {
+	var kept []sourceKeys
+	for _, keys := range unused {
+		if strings.HasPrefix(keys.Source, "values."+env+".") || strings.HasPrefix(keys.Source, "ports."+env+".") {
+			kept = append(kept, keys)
+		}
+	}
+	return kept
+}
+
+// validateEnvFiles validates the values, ports and bootstrap files of a profile,
+// returning whether any is invalid
+func validateEnvFiles(env string) bool {
+	hasErrors := false
+	files := []string{}
+	for _, name := range []string{"values", "ports"} {
+		file, err := utils.FindValuesFile(name + "." + env)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			hasErrors = true
+			continue
+		}
+		files = append(files, file)
+	}
+	files = append(files, envBootstrapFile(env))
+
+	found := false
+	for _, file := range files {
+		if !fileExists(file) {
+			continue
+		}
+		found = true
+		if err := validateFile(file, false); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			hasErrors = true
+		} else {
+			fmt.Printf("✓ %s is valid\n", file)
+		}
+	}
+	if !found && !hasErrors {
+		fmt.Fprintf(os.Stderr, "❌ environment %q has no values.%[1]s, ports.%[1]s or %s file\n", env, envBootstrapFile(env))
+		hasErrors = true
+	}
+	return hasErrors
+}
+
 // printOptionalFileStatus prints the status of an optional file
 func printOptionalFileStatus(filename string) {
 	if fileExists(filename) {
@@ -81,20 +335,31 @@ func validateFile(filename string, required bool) error {
 		return nil
 	}
 
-	// Read and validate the file based on its type
-	switch filename {
-	case "values.json":
+	// Read and validate the file based on its type, whatever its format or profile,
+	// e.g. values.prod.yaml is a values file
+	kind, _, _ := strings.Cut(filepath.Base(filename), ".")
+	switch kind {
+	case "values":
 		_, err := utils.LoadValuesFromFile(filename)
 		return err
-	case "ports.json":
+	case "ports":
 		_, err := utils.LoadValuesFromFile(filename)
 		return err
-	case "projects.json":
+	case "projects":
 		_, err := utils.LoadProjects(filename)
 		return err
-	case "config.json":
+	case "config":
 		_, err := utils.LoadTurnItOffAndOnAgainConfig(filename)
 		return err
+	case "bootstrap":
+		config, err := utils.LoadBootstrapConfig(filename)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", filename, err)
+		}
+		if _, err := config.Providers(); err != nil {
+			return fmt.Errorf("invalid %s: %w", filename, err)
+		}
+		return nil
 	default:
 		// Generic JSON validation
 		data, err := os.ReadFile(filename)
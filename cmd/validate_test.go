@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// countingSecretProvider counts how many times its secret is loaded
+type countingSecretProvider struct {
+	loads *int
+}
+
+func (p *countingSecretProvider) Name() string { return "counting secret" }
+
+func (p *countingSecretProvider) Load(ctx context.Context) (*utils.Secret, error) {
+	*p.loads++
+	return &utils.Secret{Values: map[string]interface{}{"Redis": "hunter2"}}, nil
+}
+
+func TestValidateCmd_LoadsValuesOnce(t *testing.T) {
+	loads := 0
+	utils.RegisterSecretProvider("test-counting", func(config utils.SecretProviderConfig) (utils.SecretProvider, error) {
+		return &countingSecretProvider{loads: &loads}, nil
+	})
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":        `{"Redis": {"Host": "localhost"}}`,
+		"projects.json":      `[]`,
+		"bootstrap.json":     `{"Secrets": [{"Type": "test-counting"}], "ValuesMerge": {"Mode": "deep"}}`,
+		"values.prod.json":   `{"OrgName": "acme"}`,
+		"source/a.txt.tmpl":  `{{.Redis.Host}}`,
+		"values.schema.json": `{"properties": {"OrgName": {"type": "string"}}}`,
+	})
+
+	cmd := NewValidateCmd()
+	cmd.SetArgs(nil)
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "values that cannot be merged") {
+		t.Errorf("expected the type conflict to fail validation, got %v", err)
+	}
+	// Once for the base files and once for the prod profile
+	if loads != 2 {
+		t.Errorf("secret loaded %d times, want 2", loads)
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateValueKeys returns the sorted top-level value keys referenced by tmpl.
+// It understands .Key, $.Key and index . "key" references, tracks when range and
+// with rebind the dot, and follows {{template}} calls that pass on the root context.
+func templateValueKeys(tmpl *template.Template) []string {
+	w := walkValueKeys(tmpl)
+	keys := make([]string, 0, len(w.keys))
+	for key := range w.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// templateValueKeyRefs returns every reference templateValueKeys finds, in the
+// order they appear, including references made from called partials
+func templateValueKeyRefs(tmpl *template.Template) []valueKeyRef {
+	return walkValueKeys(tmpl).refs
+}
+
+// walkValueKeys walks tmpl and the templates it calls, collecting value key references
+func walkValueKeys(tmpl *template.Template) *valueKeyWalker {
+	w := &valueKeyWalker{
+		tmpl: tmpl,
+		keys: make(map[string]bool),
+	}
+	if tmpl == nil || tmpl.Tree == nil {
+		return w
+	}
+
+	w.visited = map[string]bool{tmpl.Name(): true}
+	w.tree = tmpl.Tree
+	w.walk(tmpl.Tree.Root, true)
+	return w
+}
+
+// valueKeyRef is a single reference to a top-level value key
+type valueKeyRef struct {
+	Key  string
+	tree *parse.Tree
+	node parse.Node
+}
+
+// location returns the name the referencing template was parsed as (the file
+// name for a template, the file path for a partial) and the line of the reference
+func (r valueKeyRef) location() (string, int) {
+	loc, _ := r.tree.ErrorContext(r.node)
+	// loc is name:line:column
+	loc = loc[:strings.LastIndex(loc, ":")]
+	i := strings.LastIndex(loc, ":")
+	line, _ := strconv.Atoi(loc[i+1:])
+	return loc[:i], line
+}
+
+// valueKeyWalker collects value keys while walking a template parse tree
+type valueKeyWalker struct {
+	tmpl *template.Template
+	// tree is the parse tree currently being walked
+	tree    *parse.Tree
+	keys    map[string]bool
+	refs    []valueKeyRef
+	visited map[string]bool
+}
+
+// add records a reference to key made by node
+func (w *valueKeyWalker) add(key string, node parse.Node) {
+	w.keys[key] = true
+	w.refs = append(w.refs, valueKeyRef{Key: key, tree: w.tree, node: node})
+}
+
+// walk visits node; rootDot reports whether the dot currently refers to the values map
+func (w *valueKeyWalker) walk(node parse.Node, rootDot bool) {
+	switch n := node.(type) {
+	case nil:
+		return
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, rootDot)
+		}
+	case *parse.ActionNode:
+		w.walk(n.Pipe, rootDot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.walk(cmd, rootDot)
+		}
+	case *parse.CommandNode:
+		w.walkIndex(n, rootDot)
+		for _, arg := range n.Args {
+			w.walk(arg, rootDot)
+		}
+	case *parse.FieldNode:
+		if rootDot && len(n.Ident) > 0 {
+			w.add(n.Ident[0], n)
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			w.add(n.Ident[1], n)
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, rootDot)
+	case *parse.IfNode:
+		w.walk(n.Pipe, rootDot)
+		w.walk(n.List, rootDot)
+		w.walk(n.ElseList, rootDot)
+	case *parse.WithNode:
+		// Inside with, the dot is the pipeline's value
+		w.walk(n.Pipe, rootDot)
+		w.walk(n.List, rootDot && isDotPipe(n.Pipe))
+		w.walk(n.ElseList, rootDot)
+	case *parse.RangeNode:
+		// Inside range, the dot is each element of the pipeline's value
+		w.walk(n.Pipe, rootDot)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, rootDot)
+	case *parse.TemplateNode:
+		w.walk(n.Pipe, rootDot)
+		// Follow the call when the invoked template receives the values map as its dot
+		if rootDot && isDotPipe(n.Pipe) && !w.visited[n.Name] {
+			w.visited[n.Name] = true
+			if called := w.tmpl.Lookup(n.Name); called != nil && called.Tree != nil {
+				caller := w.tree
+				w.tree = called.Tree
+				w.walk(called.Tree.Root, true)
+				w.tree = caller
+			}
+		}
+	}
+}
+
+// walkIndex records keys looked up with index . "key" or index $ "key"
+func (w *valueKeyWalker) walkIndex(n *parse.CommandNode, rootDot bool) {
+	if len(n.Args) < 3 {
+		return
+	}
+	ident, ok := n.Args[0].(*parse.IdentifierNode)
+	if !ok || ident.Ident != "index" {
+		return
+	}
+
+	switch target := n.Args[1].(type) {
+	case *parse.DotNode:
+		if !rootDot {
+			return
+		}
+	case *parse.VariableNode:
+		if len(target.Ident) != 1 || target.Ident[0] != "$" {
+			return
+		}
+	default:
+		return
+	}
+
+	if key, ok := n.Args[2].(*parse.StringNode); ok {
+		w.add(key.Text, key)
+	}
+}
+
+// isDotPipe reports whether a pipeline evaluates to the unmodified dot (. or $)
+func isDotPipe(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return true
+	case *parse.VariableNode:
+		return len(arg.Ident) == 1 && arg.Ident[0] == "$"
+	}
+	return false
+}
+
+// mergeValueKeys returns the sorted union of two sets of value keys
+func mergeValueKeys(a, b []string) []string {
+	set := make(map[string]bool, len(a)+len(b))
+	for _, key := range a {
+		set[key] = true
+	}
+	for _, key := range b {
+		set[key] = true
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
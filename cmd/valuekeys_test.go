@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestTemplateValueKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"field", `{{ .RedisHost }}:{{ .RedisPort }}`, []string{"RedisHost", "RedisPort"}},
+		{"index", `{{ index . "github-webhook-port" }}`, []string{"github-webhook-port"}},
+		{"root variable", `{{ range .Projects }}{{ $.OrgName }}/{{ .name }}{{ end }}`, []string{"OrgName", "Projects"}},
+		{"range variable", `{{ range $i, $p := .Projects }}{{ $p.name }}{{ index $ "BaseDir" }}{{ end }}`, []string{"BaseDir", "Projects"}},
+		{"with rebinds dot", `{{ with .Redis }}{{ .Host }}{{ else }}{{ .Fallback }}{{ end }}`, []string{"Fallback", "Redis"}},
+		{"if keeps dot", `{{ if .Enabled }}{{ .Name }}{{ end }}`, []string{"Enabled", "Name"}},
+		{"function args", `{{ printf "%s/%s" .BaseDir .OrgName | toJson }}`, []string{"BaseDir", "OrgName"}},
+		{"pipeline into default", `{{ .Port | default 8080 }}`, []string{"Port"}},
+		{"nested field", `{{ .Redis.Host }}`, []string{"Redis"}},
+		{"no keys", `static`, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New(tt.name).Funcs(templateFuncs()).Parse(tt.text))
+			if got := templateValueKeys(tmpl); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("templateValueKeys() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplateValueKeys_FollowsPartials(t *testing.T) {
+	partials := template.Must(template.New(partialsDirName).Funcs(templateFuncs()).Parse(
+		`{{ define "redis" }}{{ .RedisHost }}{{ end }}{{ define "env" }}{{ .ListName }}{{ end }}`))
+	tmpl := template.Must(template.Must(partials.Clone()).New("config.tmpl").Parse(
+		`{{ template "redis" . }} {{ template "env" dict "ListName" .PoppitListName }}`))
+
+	expected := []string{"PoppitListName", "RedisHost"}
+	if got := templateValueKeys(tmpl); !reflect.DeepEqual(got, expected) {
+		t.Errorf("templateValueKeys() = %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+// valueSource is one of the sources template values are merged from
+type valueSource struct {
+	// Name identifies the source, e.g. "values.json"
+	Name   string
+	Values map[string]interface{}
+	// Secret marks a source whose values must not be shown, such as a GCP secret
+	Secret bool
+	// Literal marks a source whose strings are used as they are, without
+	// resolving ${Key} references, such as projects.json or --set
+	Literal bool
+}
+
+// valuesSchemaFile declares the required keys, types, defaults and secrets of the values
+const valuesSchemaFile = "values.schema.json"
+
+// valueSet is the sources of template values and how bootstrap.json says to merge them
+type valueSet struct {
+	// Sources are in merge order, later sources overriding earlier ones
+	Sources []valueSource
+	// Secret is the provenance of the secrets, if any were loaded
+	Secret *secretProvenance
+	Merge  utils.MergeOptions
+}
+
+// loadTemplateValues loads the values every template is rendered with: values.json,
+// projects.json (as Projects), ports.json (each in JSON, YAML or TOML), the files
+// of the env profile if one is given and the secrets of the providers bootstrap.json
+// selects. The overrides from the command line and environment are applied last,
+// then the schema's defaults. ${Key} references are resolved once every layer is
+// merged, in the values and ports files only, and the result is checked against the schema if there is one. The
+// secrets' provenance is returned for the build manifest.
+func loadTemplateValues(env string, overrides []valueSource, schema *utils.ValuesSchema) (map[string]interface{}, *secretProvenance, error) {
+	set, err := loadValueSources(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged, err := mergeValueSources(set.Sources, set.Merge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged = applyValueOverrides(os.Stderr, merged, set.Sources, overrides)
+	literal := literalPaths(append(set.Sources, overrides...))
+	if merged, err = resolveValues(merged, literal, schema); err != nil {
+		return nil, nil, err
+	}
+	if set.Secret != nil {
+		// An overridden key no longer comes from the secret
+		for _, override := range overrides {
+			for key := range override.Values {
+				delete(set.Secret.Keys, key)
+			}
+		}
+	}
+	return merged, set.Secret, nil
+}
+
+// loadValueSources loads each source of template values, in merge order: the base
+// files, then values.<env> and ports.<env> for a profile, then the secrets of the
+// providers bootstrap.json and bootstrap.<env>.json select
+func loadValueSources(env string) (*valueSet, error) {
+	// Each file may be JSON, YAML or TOML, e.g. values.json or values.yaml
+	valuesFile, err := utils.FindValuesFile("values")
+	if err != nil {
+		return nil, err
+	}
+	projectsFile, err := utils.FindValuesFile("projects")
+	if err != nil {
+		return nil, err
+	}
+	portsFile, err := utils.FindValuesFile("ports")
+	if err != nil {
+		return nil, err
+	}
+
+	// Load values from values.json
+	values, err := utils.LoadValuesFromFile(valuesFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", valuesFile, err)
+	}
+
+	// Load projects as []map[string]interface{} for template use
+	projectsList, err := utils.LoadProjectsMap(projectsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", projectsFile, err)
+	}
+
+	// Load ports from ports.json (optional)
+	ports, err := utils.LoadValuesFromFile(portsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", portsFile, err)
+	}
+
+	sources := []valueSource{
+		{Name: valuesFile, Values: values},
+		{Name: projectsFile, Values: map[string]interface{}{"Projects": projectsList}, Literal: true},
+		{Name: portsFile, Values: ports},
+	}
+
+	// Load bootstrap config (optional, but an invalid one is an error)
+	bootstrapConfig := &utils.BootstrapConfig{}
+	if fileExists("bootstrap.json") {
+		if bootstrapConfig, err = utils.LoadBootstrapConfig("bootstrap.json"); err != nil {
+			return nil, fmt.Errorf("error loading bootstrap.json: %w", err)
+		}
+	}
+	set := &valueSet{Merge: bootstrapConfig.ValuesMerge}
+	bootstraps := []*utils.BootstrapConfig{bootstrapConfig}
+
+	// Layer the profile's files over the base files
+	if env != "" {
+		envSources, envBootstrap, err := loadEnvSources(env)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, envSources...)
+		if envBootstrap != nil {
+			bootstraps = append(bootstraps, envBootstrap)
+			if envBootstrap.ValuesMerge != (utils.MergeOptions{}) {
+				set.Merge = envBootstrap.ValuesMerge
+			}
+		}
+	}
+
+	// Secrets are merged last so they override local values, the profile's
+	// secrets overriding the base ones
+	ctx := context.Background()
+	for _, bootstrap := range bootstraps {
+		providers, err := bootstrap.Providers()
+		if err != nil {
+			return nil, fmt.Errorf("invalid bootstrap config: %w", err)
+		}
+		for _, provider := range providers {
+			secret, err := provider.Load(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error loading %s: %w", provider.Name(), err)
+			}
+			fmt.Fprintf(os.Stderr, "Loaded %d values from %s\n", len(secret.Values), provider.Name())
+			sources = append(sources, valueSource{Name: provider.Name(), Values: secret.Values, Secret: true})
+
+			// Remember which keys came from a secret for the build manifest
+			if set.Secret == nil {
+				set.Secret = &secretProvenance{Keys: make(map[string]string)}
+			}
+			for key := range secret.Values {
+				set.Secret.Keys[key] = secret.Version
+			}
+		}
+	}
+
+	set.Sources = sources
+	return set, nil
+}
+
+// mergeValueSources merges sources in order, later sources overriding earlier ones.
+// With a deep merge, nested maps are merged rather than replaced and a type
+// conflict between two sources is an error naming both.
+func mergeValueSources(sources []valueSource, opts utils.MergeOptions) (map[string]interface{}, error) {
+	layers := make([]utils.ValueLayer, len(sources))
+	for i, source := range sources {
+		layers[i] = utils.ValueLayer{Name: source.Name, Values: source.Values}
+	}
+	merged, err := utils.MergeLayers(layers, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error merging values: %w", err)
+	}
+	return merged, nil
+}
+
+// loadValuesSchema loads values.schema.json, returning nil when there is none
+func loadValuesSchema() (*utils.ValuesSchema, error) {
+	schema, err := utils.LoadValuesSchema(valuesSchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", valuesSchemaFile, err)
+	}
+	return schema, nil
+}
+
+// literalPaths returns the dotted paths of the values that come from a secret or
+// literal source and so must not be interpolated, mapped to true, given sources
+// in merge order. A path a later, interpolated source sets is mapped to false.
+func literalPaths(sources []valueSource) map[string]bool {
+	literal := make(map[string]bool)
+	for _, source := range sources {
+		markLiteralPaths(literal, "", source.Values, source.Secret || source.Literal)
+	}
+	return literal
+}
+
+// markLiteralPaths records whether each leaf of values, under prefix, is literal.
+// Lists are leaves: a list is replaced as a whole when sources are merged.
+func markLiteralPaths(literal map[string]bool, prefix string, values map[string]interface{}, isLiteral bool) {
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			markLiteralPaths(literal, path, nested, isLiteral)
+			continue
+		}
+		literal[path] = isLiteral
+	}
+}
+
+// valueReferences returns the top-level keys each of values references with
+// ${Key}, ignoring the strings at the literal paths
+func valueReferences(values map[string]interface{}, literal map[string]bool) map[string][]string {
+	references := make(map[string][]string)
+	for key, value := range values {
+		if keys := utils.ReferencedKeys(withoutLiteral(key, value, literal)); len(keys) > 0 {
+			references[key] = keys
+		}
+	}
+	return references
+}
+
+// withoutLiteral returns value, found at path, without the values at the literal paths
+func withoutLiteral(path string, value interface{}, literal map[string]bool) interface{} {
+	if literal[path] {
+		return nil
+	}
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	kept := make(map[string]interface{}, len(nested))
+	for key, item := range nested {
+		kept[key] = withoutLiteral(path+"."+key, item, literal)
+	}
+	return kept
+}
+
+// resolveValues fills in the schema's defaults for missing keys, resolves the
+// ${Key} references between values, except at the literal paths, and checks the
+// result against the schema, reporting every violation. A nil schema accepts any values.
+func resolveValues(values map[string]interface{}, literal map[string]bool, schema *utils.ValuesSchema) (map[string]interface{}, error) {
+	if schema != nil {
+		values = schema.ApplyDefaults(values)
+	}
+	values, err := utils.InterpolateValues(values, literal)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving values: %w", err)
+	}
+	if schema == nil {
+		return values, nil
+	}
+	if violations := schema.Validate(values); len(violations) > 0 {
+		lines := make([]string, len(violations))
+		for i, violation := range violations {
+			lines[i] = "  " + violation.String()
+		}
+		return nil, fmt.Errorf("values do not match %s:\n%s", valuesSchemaFile, strings.Join(lines, "\n"))
+	}
+	return values, nil
+}
+
+// parseSetValues parses Key=Value overrides, as given to --set
+func parseSetValues(pairs []string) (map[string]interface{}, error) {
+	overrides := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set value %q: expected Key=Value", pair)
+		}
+		overrides[key] = value
+	}
+	return overrides, nil
+}
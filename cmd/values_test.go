@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/its-the-vibe/VibeOps/internal/utils"
+)
+
+func TestLoadTemplateValues_Schema(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":   `{"OrgName": "org", "SlackChannelID": "C123"}`,
+		"projects.json": `[]`,
+		valuesSchemaFile: `{
+  "required": ["OrgName"],
+  "properties": {
+    "SlackChannelID": {"type": "string", "pattern": "^C[0-9A-Z]+$"},
+    "RedisHost": {"type": "string", "default": "localhost"}
+  }
+}`,
+	})
+	schema, err := loadValuesSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values, _, err := loadTemplateValues("", nil, schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["RedisHost"] != "localhost" || values["OrgName"] != "org" {
+		t.Errorf("expected the schema default to be applied, got %v", values)
+	}
+
+	// The schema is enforced after the overrides are applied
+	overrides := []valueSource{{Name: "--set", Values: map[string]interface{}{"SlackChannelID": "general"}}}
+	_, _, err = loadTemplateValues("", overrides, schema)
+	if err == nil || !strings.Contains(err.Error(), "SlackChannelID: does not match pattern ^C[0-9A-Z]+$") {
+		t.Errorf("expected a schema violation, got %v", err)
+	}
+}
+
+func TestLoadTemplateValues_Interpolation(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":   `{"RedisHost": "localhost", "RedisAddr": "${RedisHost}:${RedisPort}"}`,
+		"projects.json": `[]`,
+		"ports.json":    `{"RedisPort": 6379}`,
+	})
+
+	values, _, err := loadTemplateValues("", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["RedisAddr"] != "localhost:6379" {
+		t.Errorf("RedisAddr = %v, want localhost:6379", values["RedisAddr"])
+	}
+
+	// References are resolved after the overrides, so they see the overridden value
+	overrides := []valueSource{{Name: "--set", Values: map[string]interface{}{"RedisHost": "redis.internal"}}}
+	if values, _, err = loadTemplateValues("", overrides, nil); err != nil || values["RedisAddr"] != "redis.internal:6379" {
+		t.Errorf("RedisAddr = %v, %v, want redis.internal:6379", values["RedisAddr"], err)
+	}
+
+	// --set is used as it is, while a values file may reference other values
+	overrides = []valueSource{{Name: "--set", Values: map[string]interface{}{"RedisHost": "$${HOST}"}, Literal: true}}
+	if values, _, err = loadTemplateValues("", overrides, nil); err != nil || values["RedisAddr"] != "$${HOST}:6379" {
+		t.Errorf("RedisAddr = %v, %v, want $${HOST}:6379", values["RedisAddr"], err)
+	}
+	overrides = []valueSource{{Name: "--values-file extra.json", Values: map[string]interface{}{"RedisHost": "${RedisAddr}"}}}
+	_, _, err = loadTemplateValues("", overrides, nil)
+	if err == nil || !strings.Contains(err.Error(), "RedisAddr: reference cycle RedisAddr -> RedisHost -> RedisAddr") {
+		t.Errorf("expected a reference cycle, got %v", err)
+	}
+}
+
+func TestLoadTemplateValues_LiteralSources(t *testing.T) {
+	utils.RegisterSecretProvider("test-literal", func(config utils.SecretProviderConfig) (utils.SecretProvider, error) {
+		return &fakeSecretProvider{name: config.Path, values: map[string]interface{}{"RedisPassword": "pa$${ss"}}, nil
+	})
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":    `{"RedisURL": "redis://:${RedisPassword}@localhost"}`,
+		"projects.json":  `[{"name": "Poppit", "buildCommands": ["docker build -t ${IMAGE} ."]}]`,
+		"bootstrap.json": `{"Secrets": [{"Type": "test-literal", "Path": "base"}]}`,
+	})
+
+	values, _, err := loadTemplateValues("", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	projects := values["Projects"].([]map[string]interface{})
+	if commands := projects[0]["buildCommands"].([]interface{}); commands[0] != "docker build -t ${IMAGE} ." {
+		t.Errorf("buildCommands = %v, want them untouched", commands)
+	}
+	if values["RedisPassword"] != "pa$${ss" {
+		t.Errorf("RedisPassword = %v, want the secret untouched", values["RedisPassword"])
+	}
+	if values["RedisURL"] != "redis://:pa$${ss@localhost" {
+		t.Errorf("RedisURL = %v, want the secret as it is", values["RedisURL"])
+	}
+}
+
+// fakeSecretProvider returns fixed values, standing in for a secret store
+type fakeSecretProvider struct {
+	name   string
+	values map[string]interface{}
+}
+
+func (p *fakeSecretProvider) Name() string { return "fake secret " + p.name }
+
+func (p *fakeSecretProvider) Load(ctx context.Context) (*utils.Secret, error) {
+	return &utils.Secret{Values: p.values, Version: p.name + "@2"}, nil
+}
+
+func TestLoadValueSources_SecretProviders(t *testing.T) {
+	utils.RegisterSecretProvider("test-fake", func(config utils.SecretProviderConfig) (utils.SecretProvider, error) {
+		values := map[string]interface{}{"RedisPassword": config.Path}
+		if config.Path == "prod" {
+			values["SlackBotToken"] = "xoxb-prod"
+		}
+		return &fakeSecretProvider{name: config.Path, values: values}, nil
+	})
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"values.json":         `{"RedisPassword": "local"}`,
+		"projects.json":       `[]`,
+		"bootstrap.json":      `{"Secrets": [{"Type": "test-fake", "Path": "base"}]}`,
+		"bootstrap.prod.json": `{"Secrets": [{"Type": "test-fake", "Path": "prod"}]}`,
+	})
+
+	set, err := loadValueSources("prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last := set.Sources[len(set.Sources)-2:]
+	if last[0].Name != "fake secret base" || last[1].Name != "fake secret prod" || !last[0].Secret || !last[1].Secret {
+		t.Errorf("expected both secrets to be loaded last, base first, got %+v", last)
+	}
+	expected := map[string]string{"RedisPassword": "prod@2", "SlackBotToken": "prod@2"}
+	if !reflect.DeepEqual(set.Secret.Keys, expected) {
+		t.Errorf("secret provenance = %v, want %v", set.Secret.Keys, expected)
+	}
+
+	values, err := mergeValueSources(set.Sources, set.Merge)
+	if err != nil || values["RedisPassword"] != "prod" {
+		t.Errorf("RedisPassword = %v, %v, want the profile's secret", values["RedisPassword"], err)
+	}
+
+	writeTestFiles(t, dir, map[string]string{"bootstrap.json": `{"Secrets": [{"Type": "vault"}]}`})
+	if _, err := loadValueSources(""); err == nil || !strings.Contains(err.Error(), `unknown secret provider type "vault"`) {
+		t.Errorf("expected an unknown provider error, got %v", err)
+	}
+	writeTestFiles(t, dir, map[string]string{"bootstrap.json": `{"Secrets": [`})
+	if _, err := loadValueSources(""); err == nil || !strings.Contains(err.Error(), "error loading bootstrap.json") {
+		t.Errorf("expected an invalid bootstrap.json to be reported, got %v", err)
+	}
+}
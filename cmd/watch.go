@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// watchDebounce is how long watched files must stay unchanged before re-rendering,
+// so an editor saving several files at once triggers a single render
+const watchDebounce = 300 * time.Millisecond
+
+// fileStamp is the state of a watched file used to detect changes
+type fileStamp struct {
+	// ModTime is the modification time in nanoseconds since the epoch
+	ModTime int64
+	Size    int64
+	Dir     bool
+}
+
+// watchSnapshot maps every watched path to its stamp
+type watchSnapshot map[string]fileStamp
+
+// watchChanges lists the paths that changed between two snapshots, each sorted
+type watchChanges struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Empty reports whether nothing changed
+func (c watchChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// templateWatcher re-renders the build whenever the source layers or the values
+// files change, polling for changes so it needs no platform-specific notifications
+type templateWatcher struct {
+	SourceDirs     []string
+	FollowSymlinks bool
+	// ValueFiles are the files whose changes reload the values and re-render everything
+	ValueFiles []string
+	// Interval is how often the watched files are polled
+	Interval time.Duration
+	// Debounce is how long the watched files must stay unchanged before re-rendering
+	Debounce time.Duration
+	// Load loads the values and options for a render
+	Load func() (map[string]interface{}, templateOptions, error)
+	// Render renders the build; opts.Only limits it to the changed templates
+	Render func(values map[string]interface{}, opts templateOptions) error
+}
+
+// Run renders the build once and then again after every change until ctx is done.
+// Errors are reported and watching continues, so a broken template can be fixed
+// without restarting the watcher.
+func (w *templateWatcher) Run(ctx context.Context) error {
+	snapshot := w.snapshot()
+	values, opts, err := w.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		values = nil
+	} else if err := w.Render(values, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	fmt.Println("Watching for changes (press Ctrl+C to stop)...")
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current := w.snapshot()
+		if diffSnapshots(snapshot, current).Empty() {
+			continue
+		}
+		current, ok := w.settle(ctx, current)
+		if !ok {
+			return nil
+		}
+		changes := diffSnapshots(snapshot, current)
+		snapshot = current
+		if changes.Empty() {
+			continue
+		}
+
+		printWatchChanges(changes)
+		reload, only := w.plan(changes)
+		if reload || values == nil {
+			if values, opts, err = w.Load(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				values = nil
+				continue
+			}
+		}
+
+		renderOpts := opts
+		renderOpts.Only = only
+		if only != nil {
+			fmt.Printf("Re-rendering %d changed template(s)\n", len(only))
+		} else {
+			fmt.Println("Re-rendering all templates")
+		}
+		if err := w.Render(values, renderOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// settle polls until the watched files stay unchanged for the debounce period and
+// returns the final snapshot; it returns false if ctx is done first
+func (w *templateWatcher) settle(ctx context.Context, current watchSnapshot) (watchSnapshot, bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-time.After(w.Debounce):
+		}
+		next := w.snapshot()
+		if diffSnapshots(current, next).Empty() {
+			return next, true
+		}
+		current = next
+	}
+}
+
+// plan decides what a set of changes requires. A changed values file reloads the
+// values and re-renders everything; so does any change in the source layers other
+// than an edit to an existing template, since partials, new or removed templates
+// and directories can affect any output. Otherwise only the edited templates are
+// returned for re-rendering.
+func (w *templateWatcher) plan(changes watchChanges) (reload bool, only map[string]bool) {
+	valueFiles := make(map[string]bool, len(w.ValueFiles))
+	for _, file := range w.ValueFiles {
+		valueFiles[file] = true
+	}
+	for _, paths := range [][]string{changes.Added, changes.Removed, changes.Modified} {
+		for _, path := range paths {
+			if valueFiles[path] {
+				return true, nil
+			}
+		}
+	}
+	if len(changes.Added) > 0 || len(changes.Removed) > 0 {
+		return false, nil
+	}
+
+	only = make(map[string]bool, len(changes.Modified))
+	for _, path := range changes.Modified {
+		if !strings.HasSuffix(path, ".tmpl") || isPartialPath(w.layerRelPath(path)) {
+			return false, nil
+		}
+		only[path] = true
+	}
+	return false, only
+}
+
+// layerRelPath returns path relative to the source layer containing it
+func (w *templateWatcher) layerRelPath(path string) string {
+	for _, sourceDir := range w.SourceDirs {
+		if relPath, err := filepath.Rel(sourceDir, path); err == nil && filepath.IsLocal(relPath) {
+			return relPath
+		}
+	}
+	return path
+}
+
+// snapshot stamps the values files and every file and directory in the source
+// layers. Paths that cannot be read are left out, so they show up as removed.
+func (w *templateWatcher) snapshot() watchSnapshot {
+	snapshot := make(watchSnapshot)
+	for _, file := range w.ValueFiles {
+		if info, err := os.Stat(file); err == nil {
+			snapshot[file] = fileStamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+		}
+	}
+
+	for _, sourceDir := range w.SourceDirs {
+		walkFn := func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Skip unreadable entries; a missing layer is simply empty
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				snapshot[path] = fileStamp{Dir: true}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			snapshot[path] = fileStamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+			return nil
+		}
+		if w.FollowSymlinks {
+			walkDirFollowSymlinks(sourceDir, walkFn)
+		} else {
+			filepath.WalkDir(sourceDir, walkFn)
+		}
+	}
+	return snapshot
+}
+
+// diffSnapshots lists the paths added, removed and modified between two snapshots
+func diffSnapshots(prev, cur watchSnapshot) watchChanges {
+	var changes watchChanges
+	for path, stamp := range cur {
+		previous, ok := prev[path]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, path)
+		case previous != stamp:
+			changes.Modified = append(changes.Modified, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := cur[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+	return changes
+}
+
+// printWatchChanges prints a one-line-per-path summary of detected changes
+func printWatchChanges(changes watchChanges) {
+	fmt.Printf("\n[%s] Detected changes:\n", time.Now().Format("15:04:05"))
+	for _, path := range changes.Added {
+		fmt.Printf("  added:    %s\n", path)
+	}
+	for _, path := range changes.Removed {
+		fmt.Printf("  removed:  %s\n", path)
+	}
+	for _, path := range changes.Modified {
+		fmt.Printf("  modified: %s\n", path)
+	}
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	prev := watchSnapshot{
+		"source/a.tmpl": {ModTime: 1, Size: 10},
+		"source/b.tmpl": {ModTime: 1, Size: 10},
+		"source/dir":    {Dir: true},
+		"values.json":   {ModTime: 1, Size: 5},
+	}
+	cur := watchSnapshot{
+		"source/a.tmpl": {ModTime: 1, Size: 10},
+		"source/b.tmpl": {ModTime: 2, Size: 10},
+		"source/c.tmpl": {ModTime: 1, Size: 1},
+		"values.json":   {ModTime: 1, Size: 6},
+	}
+
+	expected := watchChanges{
+		Added:    []string{"source/c.tmpl"},
+		Removed:  []string{"source/dir"},
+		Modified: []string{"source/b.tmpl", "values.json"},
+	}
+	if got := diffSnapshots(prev, cur); !reflect.DeepEqual(got, expected) {
+		t.Errorf("diffSnapshots() = %+v, want %+v", got, expected)
+	}
+	if !diffSnapshots(cur, cur).Empty() {
+		t.Error("expected no changes between identical snapshots")
+	}
+}
+
+func TestTemplateWatcherPlan(t *testing.T) {
+	w := &templateWatcher{SourceDirs: []string{"source", "private"}, ValueFiles: []string{"values.json", "ports.json"}}
+
+	tests := []struct {
+		name    string
+		changes watchChanges
+		reload  bool
+		only    map[string]bool
+	}{
+		{"edited templates", watchChanges{Modified: []string{"source/a.tmpl", "private/b.tmpl"}}, false, map[string]bool{"source/a.tmpl": true, "private/b.tmpl": true}},
+		{"values file", watchChanges{Modified: []string{"source/a.tmpl", "ports.json"}}, true, nil},
+		{"values file created", watchChanges{Added: []string{"ports.json"}}, true, nil},
+		{"edited partial", watchChanges{Modified: []string{"source/_partials/redis.tmpl"}}, false, nil},
+		{"edited partial file", watchChanges{Modified: []string{"private/svc/db.partial.tmpl"}}, false, nil},
+		{"new template", watchChanges{Added: []string{"source/c.tmpl"}}, false, nil},
+		{"removed template", watchChanges{Removed: []string{"source/c.tmpl"}}, false, nil},
+		{"other file", watchChanges{Modified: []string{"source/README.md"}}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reload, only := w.plan(tt.changes)
+			if reload != tt.reload || !reflect.DeepEqual(only, tt.only) {
+				t.Errorf("plan() = %v, %v, want %v, %v", reload, only, tt.reload, tt.only)
+			}
+		})
+	}
+}
+
+func TestTemplateWatcherRun(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := filepath.Join(root, "source")
+	valuesFile := filepath.Join(root, "values.json")
+	writeTestFiles(t, root, map[string]string{
+		"source/a.txt.tmpl": "a",
+		"source/b.txt.tmpl": "b",
+		"values.json":       "{}",
+	})
+
+	type render struct {
+		loads int
+		only  map[string]bool
+	}
+	renders := make(chan render, 10)
+	loads := 0
+	w := &templateWatcher{
+		SourceDirs: []string{sourceDir},
+		ValueFiles: []string{valuesFile},
+		Interval:   5 * time.Millisecond,
+		Debounce:   20 * time.Millisecond,
+		Load: func() (map[string]interface{}, templateOptions, error) {
+			loads++
+			return map[string]interface{}{}, templateOptions{}, nil
+		},
+		Render: func(values map[string]interface{}, opts templateOptions) error {
+			renders <- render{loads: loads, only: opts.Only}
+			return os.ErrInvalid
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	next := func() render {
+		t.Helper()
+		select {
+		case r := <-renders:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a render")
+			return render{}
+		}
+	}
+
+	// The initial full render fails, but the watcher keeps going
+	if r := next(); r.loads != 1 || r.only != nil {
+		t.Fatalf("initial render = %+v, want a full render after one load", r)
+	}
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "a.txt.tmpl"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r := next(); r.loads != 1 || !reflect.DeepEqual(r.only, map[string]bool{filepath.Join(sourceDir, "a.txt.tmpl"): true}) {
+		t.Fatalf("render after a template edit = %+v, want only a.txt.tmpl without reloading", r)
+	}
+
+	if err := os.WriteFile(valuesFile, []byte(`{"Key": "value"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r := next(); r.loads != 2 || r.only != nil {
+		t.Fatalf("render after a values change = %+v, want a full render after reloading", r)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not stop after cancellation")
+	}
+}
+
+func TestProcessTemplates_OnlyKeepsOtherOutputs(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := filepath.Join(root, "source")
+	buildDir := filepath.Join(root, "build")
+	writeTestFiles(t, sourceDir, map[string]string{
+		"a.txt.tmpl": "a {{ .Value }}",
+		"b.txt.tmpl": "b {{ .Value }}",
+	})
+	if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{"Value": "1"}, templateOptions{}); err != nil {
+		t.Fatalf("initial render failed: %v", err)
+	}
+
+	only := map[string]bool{filepath.Join(sourceDir, "a.txt.tmpl"): true}
+	result, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{"Value": "2"}, templateOptions{Only: only, Prune: true})
+	if err != nil {
+		t.Fatalf("partial render failed: %v", err)
+	}
+	if len(result.Outputs) != 1 || result.Outputs[0].RelPath != "a.txt" {
+		t.Errorf("expected only a.txt to be rendered, got %+v", result.Outputs)
+	}
+
+	for name, expected := range map[string]string{"a.txt": "a 2", "b.txt": "b 1"} {
+		content, err := os.ReadFile(filepath.Join(buildDir, name))
+		if err != nil || string(content) != expected {
+			t.Errorf("%s = %q (%v), want %q", name, content, err, expected)
+		}
+	}
+	manifest, err := loadManifest(buildDir)
+	if err != nil || manifest == nil || len(manifest.Files) != 2 {
+		t.Fatalf("expected the manifest to keep both outputs, got %+v (%v)", manifest, err)
+	}
+}
+
+func TestProcessTemplates_OnlyRemovesMovedOutput(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := filepath.Join(root, "source")
+	buildDir := filepath.Join(root, "build")
+	writeTestFiles(t, sourceDir, map[string]string{
+		"a.txt.tmpl": "---\npath: old/a.txt\n---\na",
+		"b.txt.tmpl": "b",
+	})
+	if _, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{}); err != nil {
+		t.Fatalf("initial render failed: %v", err)
+	}
+
+	// The watcher re-renders only a.txt.tmpl after its front matter moves the output
+	writeTestFiles(t, sourceDir, map[string]string{"a.txt.tmpl": "---\npath: new/a.txt\n---\na"})
+	only := map[string]bool{filepath.Join(sourceDir, "a.txt.tmpl"): true}
+	result, err := processTemplates([]string{sourceDir}, buildDir, map[string]interface{}{}, templateOptions{Only: only})
+	if err != nil {
+		t.Fatalf("partial render failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Pruned, []string{"old/a.txt"}) {
+		t.Errorf("Pruned = %v, want the previous output", result.Pruned)
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "old")); !os.IsNotExist(err) {
+		t.Errorf("expected the previous output and its directory to be removed, got %v", err)
+	}
+	for _, name := range []string{"new/a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(buildDir, name)); err != nil {
+			t.Errorf("expected %s to exist: %v", name, err)
+		}
+	}
+
+	manifest, err := loadManifest(buildDir)
+	if err != nil || manifest == nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	var paths []string
+	for _, entry := range manifest.Files {
+		paths = append(paths, entry.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"b.txt", "new/a.txt"}) {
+		t.Errorf("manifest paths = %v, want [b.txt new/a.txt]", paths)
+	}
+}
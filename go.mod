@@ -4,6 +4,7 @@ go 1.27.0
 
 require (
 	cloud.google.com/go/secretmanager v1.21.0
+	github.com/BurntSushi/toml v1.6.0
 	github.com/spf13/cobra v1.10.2
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// referencePattern matches a ${Key} reference to another value, or $${ escaping
+// a literal ${
+var referencePattern = regexp.MustCompile(`\$\$\{|\$\{([^{}]*)\}`)
+
+// referenceKeyPattern is the form of a reference: a top-level key, optionally
+// followed by the keys of nested maps, e.g. Redis.Host
+var referenceKeyPattern = regexp.MustCompile(`^[^.\s{}$]+(\.[^.\s{}$]+)*$`)
+
+// InterpolationProblem is a reference that could not be resolved
+type InterpolationProblem struct {
+	// Path is the dotted path of the value containing the reference, e.g. "Redis.Addr"
+	Path    string
+	Message string
+}
+
+// InterpolationError reports every reference that could not be resolved
+type InterpolationError struct {
+	Problems []InterpolationProblem
+}
+
+func (e *InterpolationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d unresolved reference(s) in values:", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s", p.Path, p.Message)
+	}
+	return b.String()
+}
+
+// InterpolateValues resolves the ${Key} references in every string of values,
+// including strings nested in maps and lists, and returns the resolved copy.
+// A reference may name a nested value with a dotted path, e.g. ${Redis.Host}.
+// A string that is exactly one reference takes the referenced value with its
+// type; otherwise the referenced value must be a string, number or boolean and
+// is formatted into the string. $${ stands for a literal ${. Undefined keys and
+// reference cycles are reported together in an *InterpolationError.
+//
+// The values at the dotted paths in literal, e.g. "Projects" or "Redis.Password",
+// are used as they are: their strings are neither resolved nor unescaped, though
+// other values may still reference them.
+func InterpolateValues(values map[string]interface{}, literal map[string]bool) (map[string]interface{}, error) {
+	in := newInterpolator(values)
+	in.literal = literal
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	// Resolve in a stable order so a cycle is always reported the same way
+	sort.Strings(keys)
+
+	resolved := make(map[string]interface{}, len(values))
+	for _, key := range keys {
+		resolved[key] = in.resolve(key, values[key])
+	}
+	if err := in.err(); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// ResolveValue resolves the references of a single top-level key, ignoring any
+// problem in values it does not depend on. It returns false when key is not defined.
+func ResolveValue(values map[string]interface{}, key string) (interface{}, bool, error) {
+	value, ok := values[key]
+	if !ok {
+		return nil, false, nil
+	}
+	in := newInterpolator(values)
+	resolved := in.resolve(key, value)
+	if err := in.err(); err != nil {
+		return nil, true, err
+	}
+	return resolved, true, nil
+}
+
+// ReferencedKeys returns the sorted top-level keys referenced by the strings in value
+func ReferencedKeys(value interface{}) []string {
+	found := make(map[string]bool)
+	walkStrings(value, func(s string) {
+		for _, m := range referencePattern.FindAllStringSubmatch(s, -1) {
+			if m[0] != "$${" && referenceKeyPattern.MatchString(m[1]) {
+				key, _, _ := strings.Cut(m[1], ".")
+				found[key] = true
+			}
+		}
+	})
+
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// walkStrings calls fn with every string in value, including those nested in maps and lists
+func walkStrings(value interface{}, fn func(string)) {
+	switch v := value.(type) {
+	case string:
+		fn(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	}
+}
+
+// interpolator resolves references against a set of values, resolving each
+// value at most once
+type interpolator struct {
+	values map[string]interface{}
+	// resolved holds the resolved value of each path
+	resolved map[string]interface{}
+	// literal holds the paths whose values are used as they are
+	literal map[string]bool
+	// stack is the paths being resolved, outermost first, to detect cycles
+	stack     []string
+	resolving map[string]bool
+	problems  []InterpolationProblem
+}
+
+func newInterpolator(values map[string]interface{}) *interpolator {
+	return &interpolator{
+		values:    values,
+		resolved:  make(map[string]interface{}),
+		resolving: make(map[string]bool),
+	}
+}
+
+// resolve returns value, found at path, with its references resolved. A literal
+// value, or one that is part of a cycle, is left unresolved and a cycle is recorded.
+func (in *interpolator) resolve(path string, value interface{}) interface{} {
+	if in.literal[path] {
+		return value
+	}
+	if result, ok := in.resolved[path]; ok {
+		return result
+	}
+	if in.resolving[path] {
+		start := 0
+		for i, p := range in.stack {
+			if p == path {
+				start = i
+				break
+			}
+		}
+		cycle := append(append([]string{}, in.stack[start:]...), path)
+		in.problem(path, "reference cycle "+strings.Join(cycle, " -> "))
+		return value
+	}
+
+	in.resolving[path] = true
+	in.stack = append(in.stack, path)
+	defer func() {
+		in.stack = in.stack[:len(in.stack)-1]
+		delete(in.resolving, path)
+	}()
+
+	var result interface{}
+	switch v := value.(type) {
+	case string:
+		result = in.resolveString(path, v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = in.resolve(path+"."+key, item)
+		}
+		result = m
+	case []map[string]interface{}:
+		list := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			list[i], _ = in.resolve(fmt.Sprintf("%s[%d]", path, i), item).(map[string]interface{})
+		}
+		result = list
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = in.resolve(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		result = list
+	default:
+		result = v
+	}
+	in.resolved[path] = result
+	return result
+}
+
+// resolveString resolves the references in the string found at path
+func (in *interpolator) resolveString(path, s string) interface{} {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	// A string that is only a reference takes the referenced value as it is
+	if m := referencePattern.FindStringSubmatch(s); m != nil && m[0] == s && m[0] != "$${" {
+		if value, ok := in.lookup(path, m[1]); ok {
+			return value
+		}
+		return s
+	}
+
+	return referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		ref := match[2 : len(match)-1]
+		value, ok := in.lookup(path, ref)
+		if !ok {
+			return match
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool, int, int64:
+			return fmt.Sprint(v)
+		}
+		in.problem(path, fmt.Sprintf("${%s} is a %s and cannot be part of a string", ref, ValueType(value)))
+		return match
+	})
+}
+
+// lookup resolves the value a reference found at path names, recording a
+// problem and returning false when it is not defined
+func (in *interpolator) lookup(path, ref string) (interface{}, bool) {
+	if !referenceKeyPattern.MatchString(ref) {
+		in.problem(path, fmt.Sprintf("invalid reference ${%s}: expected ${Key} or ${Key.Nested}", ref))
+		return nil, false
+	}
+
+	parts := strings.Split(ref, ".")
+	var value interface{} = in.values
+	for i, part := range parts {
+		// A map on the way may itself be a reference, e.g. "Redis": "${Cache}"
+		if s, ok := value.(string); ok && i > 0 {
+			value = in.resolve(strings.Join(parts[:i], "."), s)
+		}
+		m, ok := value.(map[string]interface{})
+		if ok {
+			value, ok = m[part]
+		}
+		if !ok {
+			in.problem(path, fmt.Sprintf("${%s} is not defined", ref))
+			return nil, false
+		}
+	}
+	return in.resolve(ref, value), true
+}
+
+// problem records a reference that could not be resolved
+func (in *interpolator) problem(path, message string) {
+	in.problems = append(in.problems, InterpolationProblem{Path: path, Message: message})
+}
+
+// err returns the problems recorded, sorted by path, as an *InterpolationError
+func (in *interpolator) err() error {
+	if len(in.problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(in.problems, func(i, j int) bool {
+		return in.problems[i].Path < in.problems[j].Path
+	})
+	return &InterpolationError{Problems: in.problems}
+}
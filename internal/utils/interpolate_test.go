@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInterpolateValues(t *testing.T) {
+	values := map[string]interface{}{
+		"RedisHost": "localhost",
+		"RedisPort": float64(6379),
+		"RedisAddr": "${RedisHost}:${RedisPort}",
+		"Port":      "${RedisPort}",
+		"Debug":     true,
+		"Flags":     "debug=${Debug}",
+		"Literal":   "$${RedisHost} costs $$5",
+		"Redis": map[string]interface{}{
+			"Host": "${RedisHost}",
+			"Addr": "${Redis.Host}:6379",
+		},
+		"Cache":    "${Redis}",
+		"CacheURL": "redis://${Cache.Addr}",
+		"Projects": []map[string]interface{}{{"name": "Poppit", "dir": "${BaseDir}/Poppit"}},
+		"BaseDir":  "/srv",
+	}
+
+	resolved, err := InterpolateValues(values, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"RedisHost": "localhost",
+		"RedisPort": float64(6379),
+		"RedisAddr": "localhost:6379",
+		"Port":      float64(6379),
+		"Debug":     true,
+		"Flags":     "debug=true",
+		"Literal":   "${RedisHost} costs $$5",
+		"Redis":     map[string]interface{}{"Host": "localhost", "Addr": "localhost:6379"},
+		"Cache":     map[string]interface{}{"Host": "localhost", "Addr": "localhost:6379"},
+		"CacheURL":  "redis://localhost:6379",
+		"Projects":  []map[string]interface{}{{"name": "Poppit", "dir": "/srv/Poppit"}},
+		"BaseDir":   "/srv",
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("InterpolateValues() = %v, want %v", resolved, expected)
+	}
+	if values["RedisAddr"] != "${RedisHost}:${RedisPort}" {
+		t.Error("InterpolateValues should not modify the values")
+	}
+}
+
+func TestInterpolateValues_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		expected []InterpolationProblem
+	}{
+		{
+			name:     "undefined key",
+			values:   map[string]interface{}{"RedisAddr": "${RedisHost}:6379", "Redis": map[string]interface{}{"Addr": "${Redis.Host}"}},
+			expected: []InterpolationProblem{{Path: "Redis.Addr", Message: "${Redis.Host} is not defined"}, {Path: "RedisAddr", Message: "${RedisHost} is not defined"}},
+		},
+		{
+			name:     "cycle",
+			values:   map[string]interface{}{"A": "${B}", "B": "x${C}", "C": "${A}"},
+			expected: []InterpolationProblem{{Path: "A", Message: "reference cycle A -> B -> C -> A"}},
+		},
+		{
+			name:     "self reference through a parent",
+			values:   map[string]interface{}{"Redis": map[string]interface{}{"Self": "${Redis}"}},
+			expected: []InterpolationProblem{{Path: "Redis", Message: "reference cycle Redis -> Redis.Self -> Redis"}},
+		},
+		{
+			name:     "map in a string",
+			values:   map[string]interface{}{"Redis": map[string]interface{}{}, "Addr": "redis://${Redis}"},
+			expected: []InterpolationProblem{{Path: "Addr", Message: "${Redis} is a map and cannot be part of a string"}},
+		},
+		{
+			name:     "invalid reference",
+			values:   map[string]interface{}{"Addr": "${} and ${Redis Host}"},
+			expected: []InterpolationProblem{{Path: "Addr", Message: "invalid reference ${}: expected ${Key} or ${Key.Nested}"}, {Path: "Addr", Message: "invalid reference ${Redis Host}: expected ${Key} or ${Key.Nested}"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := InterpolateValues(tt.values, nil)
+			var interpolationErr *InterpolationError
+			if !errors.As(err, &interpolationErr) {
+				t.Fatalf("expected an *InterpolationError, got %v", err)
+			}
+			if !reflect.DeepEqual(interpolationErr.Problems, tt.expected) {
+				t.Errorf("problems = %v, want %v", interpolationErr.Problems, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveValue(t *testing.T) {
+	values := map[string]interface{}{
+		"Home":    "/home/vibe",
+		"BaseDir": "${Home}/projects",
+		"Broken":  "${Missing}",
+	}
+	if value, ok, err := ResolveValue(values, "BaseDir"); err != nil || !ok || value != "/home/vibe/projects" {
+		t.Errorf("ResolveValue() = %v, %v, %v", value, ok, err)
+	}
+	if _, ok, err := ResolveValue(values, "Undefined"); err != nil || ok {
+		t.Errorf("ResolveValue() = %v, %v, want an undefined key", ok, err)
+	}
+	if _, _, err := ResolveValue(values, "Broken"); err == nil {
+		t.Error("expected an error for an undefined reference")
+	}
+}
+
+func TestReferencedKeys(t *testing.T) {
+	value := map[string]interface{}{
+		"Addr":  "${Redis.Host}:${RedisPort}",
+		"List":  []interface{}{"${BaseDir}", "$${Literal}"},
+		"Plain": "no references",
+	}
+	if got := ReferencedKeys(value); !reflect.DeepEqual(got, []string{"BaseDir", "Redis", "RedisPort"}) {
+		t.Errorf("ReferencedKeys() = %v", got)
+	}
+}
+
+func TestInterpolateValues_Literal(t *testing.T) {
+	values := map[string]interface{}{
+		"Image":    "vibe",
+		"Projects": []map[string]interface{}{{"name": "Poppit", "buildCommand": "docker build -t ${IMAGE} ."}},
+		"Redis":    map[string]interface{}{"Host": "${Image}-redis", "Password": "pa$${ss"},
+		"URL":      "redis://:${Redis.Password}@${Redis.Host}",
+	}
+	literal := map[string]bool{"Projects": true, "Redis.Password": true}
+
+	resolved, err := InterpolateValues(values, literal)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(resolved["Projects"], values["Projects"]) {
+		t.Errorf("expected Projects to be left as it is, got %v", resolved["Projects"])
+	}
+	expectedRedis := map[string]interface{}{"Host": "vibe-redis", "Password": "pa$${ss"}
+	if !reflect.DeepEqual(resolved["Redis"], expectedRedis) {
+		t.Errorf("Redis = %v, want %v", resolved["Redis"], expectedRedis)
+	}
+	if resolved["URL"] != "redis://:pa$${ss@vibe-redis" {
+		t.Errorf("URL = %v", resolved["URL"])
+	}
+}
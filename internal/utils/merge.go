@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergeMode selects how value layers are merged
+type MergeMode string
+
+const (
+	// MergeShallow replaces a top-level key wholesale (the default)
+	MergeShallow MergeMode = "shallow"
+	// MergeDeep merges nested maps key by key
+	MergeDeep MergeMode = "deep"
+)
+
+// ListMerge selects how lists are merged in deep mode
+type ListMerge string
+
+const (
+	// ListReplace replaces an earlier list with a later one (the default)
+	ListReplace ListMerge = "replace"
+	// ListAppend appends a later list to an earlier one
+	ListAppend ListMerge = "append"
+	// ListMergeByName deep merges list entries that share a "name" key and
+	// appends the rest
+	ListMergeByName ListMerge = "merge-by-name"
+)
+
+// MergeOptions configures how value layers are merged
+type MergeOptions struct {
+	Mode  MergeMode `json:"Mode"`
+	Lists ListMerge `json:"Lists"`
+}
+
+// Validate checks that the mode and list semantics are known
+func (o MergeOptions) Validate() error {
+	switch o.Mode {
+	case "", MergeShallow, MergeDeep:
+	default:
+		return fmt.Errorf("unknown merge mode %q: expected %q or %q", o.Mode, MergeShallow, MergeDeep)
+	}
+	switch o.Lists {
+	case "", ListReplace, ListAppend, ListMergeByName:
+	default:
+		return fmt.Errorf("unknown list merge %q: expected %q, %q or %q", o.Lists, ListReplace, ListAppend, ListMergeByName)
+	}
+	return nil
+}
+
+// ValueLayer is a named set of values, e.g. the contents of values.json
+type ValueLayer struct {
+	Name   string
+	Values map[string]interface{}
+}
+
+// MergeConflict is a key two layers give values of incompatible types,
+// such as a map in one and a string in the other
+type MergeConflict struct {
+	// Path is the dotted path of the key, e.g. "Redis.Host"
+	Path                     string
+	EarlierLayer, LaterLayer string
+	EarlierType, LaterType   string
+}
+
+// MergeConflictError reports every type conflict found while merging
+type MergeConflictError struct {
+	Conflicts []MergeConflict
+}
+
+func (e *MergeConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d type conflict(s) merging values:", len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		fmt.Fprintf(&b, "\n  %s: %s in %s, %s in %s", c.Path, c.EarlierType, c.EarlierLayer, c.LaterType, c.LaterLayer)
+	}
+	return b.String()
+}
+
+// MergeLayers merges layers in order, later layers taking precedence. In shallow
+// mode each top-level key is replaced wholesale, as MergeValues does. In deep mode
+// nested maps are merged key by key and lists according to opts.Lists; a key whose
+// type differs between layers (a map or list against anything else) is reported
+// as a *MergeConflictError naming both layers. The layers are never modified.
+func MergeLayers(layers []ValueLayer, opts MergeOptions) (map[string]interface{}, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Mode != MergeDeep {
+		merged := make(map[string]interface{})
+		for _, layer := range layers {
+			merged = MergeValues(merged, layer.Values)
+		}
+		return merged, nil
+	}
+
+	m := &deepMerger{lists: opts.Lists, origins: make(map[string]string)}
+	merged := make(map[string]interface{})
+	for _, layer := range layers {
+		m.mergeMaps(merged, layer.Values, "", layer.Name)
+	}
+	if len(m.conflicts) > 0 {
+		return nil, &MergeConflictError{Conflicts: m.conflicts}
+	}
+	return merged, nil
+}
+
+// deepMerger merges layers into a map it owns, remembering which layer set each path
+type deepMerger struct {
+	lists ListMerge
+	// origins maps a path to the layer that last set its whole value
+	origins   map[string]string
+	conflicts []MergeConflict
+}
+
+// mergeMaps merges src from layer into dst, which the merger owns
+func (m *deepMerger) mergeMaps(dst, src map[string]interface{}, path, layer string) {
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := src[key]
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		existing, ok := dst[key]
+		if !ok || existing == nil || value == nil {
+			dst[key] = copyValue(value)
+			m.setOrigin(keyPath, layer)
+			continue
+		}
+
+		existingMap, existingIsMap := existing.(map[string]interface{})
+		valueMap, valueIsMap := value.(map[string]interface{})
+		existingList, existingIsList := asList(existing)
+		valueList, valueIsList := asList(value)
+		switch {
+		case existingIsMap && valueIsMap:
+			m.mergeMaps(existingMap, valueMap, keyPath, layer)
+		case existingIsList && valueIsList:
+			dst[key] = m.mergeLists(existingList, valueList, keyPath, layer)
+		case existingIsMap || valueIsMap || existingIsList || valueIsList:
+			m.conflicts = append(m.conflicts, MergeConflict{
+				Path:         keyPath,
+				EarlierLayer: m.origin(keyPath),
+				LaterLayer:   layer,
+				EarlierType:  ValueType(existing),
+				LaterType:    ValueType(value),
+			})
+		default:
+			dst[key] = value
+			m.setOrigin(keyPath, layer)
+		}
+	}
+}
+
+// mergeLists merges a later list into an earlier one according to the list semantics
+func (m *deepMerger) mergeLists(dst, src []interface{}, path, layer string) []interface{} {
+	switch m.lists {
+	case ListAppend:
+		return append(dst[:len(dst):len(dst)], copyValue(src).([]interface{})...)
+	case ListMergeByName:
+		merged := dst[:len(dst):len(dst)]
+		for _, entry := range src {
+			name, ok := entryName(entry)
+			index := -1
+			if ok {
+				for i, candidate := range merged {
+					if candidateName, ok := entryName(candidate); ok && candidateName == name {
+						index = i
+						break
+					}
+				}
+			}
+			if index < 0 {
+				merged = append(merged, copyValue(entry))
+				continue
+			}
+			m.mergeMaps(merged[index].(map[string]interface{}), entry.(map[string]interface{}), fmt.Sprintf("%s[%s]", path, name), layer)
+		}
+		return merged
+	default:
+		m.setOrigin(path, layer)
+		return copyValue(src).([]interface{})
+	}
+}
+
+// setOrigin records that layer set the whole value at path, forgetting the
+// origins of anything previously nested under it
+func (m *deepMerger) setOrigin(path, layer string) {
+	for existing := range m.origins {
+		if strings.HasPrefix(existing, path+".") || strings.HasPrefix(existing, path+"[") {
+			delete(m.origins, existing)
+		}
+	}
+	m.origins[path] = layer
+}
+
+// origin returns the layer that set the value at path, or the nearest parent
+func (m *deepMerger) origin(path string) string {
+	for {
+		if layer, ok := m.origins[path]; ok {
+			return layer
+		}
+		cut := strings.LastIndexAny(path, ".[")
+		if cut < 0 {
+			return ""
+		}
+		path = path[:cut]
+	}
+}
+
+// entryName returns the "name" of a list entry that is a map with a string name
+func entryName(entry interface{}) (string, bool) {
+	entryMap, ok := entry.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	name, ok := entryMap["name"].(string)
+	return name, ok
+}
+
+// asList returns v as a list if it is one; projects are loaded as a list of maps
+func asList(v interface{}) ([]interface{}, bool) {
+	switch list := v.(type) {
+	case []interface{}:
+		return list, true
+	case []map[string]interface{}:
+		converted := make([]interface{}, len(list))
+		for i, entry := range list {
+			converted[i] = entry
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+// copyValue copies maps and lists recursively so merging never modifies a layer
+func copyValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, entry := range value {
+			copied[key] = copyValue(entry)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, entry := range value {
+			copied[i] = copyValue(entry)
+		}
+		return copied
+	case []map[string]interface{}:
+		copied := make([]interface{}, len(value))
+		for i, entry := range value {
+			copied[i] = copyValue(entry)
+		}
+		return copied
+	}
+	return v
+}
+
+// ValueType describes the type of a value, e.g. "map", "list" or "string"
+func ValueType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "map"
+	case []interface{}, []map[string]interface{}:
+		return "list"
+	case string:
+		return "string"
+	case float64, int, int64:
+		return "number"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeLayers_Shallow(t *testing.T) {
+	layers := []ValueLayer{
+		{Name: "values.json", Values: map[string]interface{}{"Redis": map[string]interface{}{"Host": "localhost"}}},
+		{Name: "secret", Values: map[string]interface{}{"Redis": map[string]interface{}{"Password": "pw"}}},
+	}
+	merged, err := MergeLayers(layers, MergeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"Redis": map[string]interface{}{"Password": "pw"}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("MergeLayers() = %v, want %v", merged, expected)
+	}
+}
+
+func TestMergeLayers_Deep(t *testing.T) {
+	values := map[string]interface{}{
+		"Redis":  map[string]interface{}{"Host": "localhost", "Port": "6379", "Options": map[string]interface{}{"DB": "0"}},
+		"Tags":   []interface{}{"a"},
+		"Remove": "x",
+	}
+	secret := map[string]interface{}{
+		"Redis":  map[string]interface{}{"Password": "pw", "Options": map[string]interface{}{"TLS": true}},
+		"Tags":   []interface{}{"b"},
+		"Remove": nil,
+	}
+	layers := []ValueLayer{{Name: "values.json", Values: values}, {Name: "secret", Values: secret}}
+
+	tests := []struct {
+		lists ListMerge
+		tags  []interface{}
+	}{
+		{"", []interface{}{"b"}},
+		{ListReplace, []interface{}{"b"}},
+		{ListAppend, []interface{}{"a", "b"}},
+		{ListMergeByName, []interface{}{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.lists), func(t *testing.T) {
+			merged, err := MergeLayers(layers, MergeOptions{Mode: MergeDeep, Lists: tt.lists})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := map[string]interface{}{
+				"Redis": map[string]interface{}{
+					"Host": "localhost", "Port": "6379", "Password": "pw",
+					"Options": map[string]interface{}{"DB": "0", "TLS": true},
+				},
+				"Tags":   tt.tags,
+				"Remove": nil,
+			}
+			if !reflect.DeepEqual(merged, expected) {
+				t.Errorf("MergeLayers() = %v, want %v", merged, expected)
+			}
+		})
+	}
+
+	if _, ok := values["Redis"].(map[string]interface{})["Password"]; ok {
+		t.Error("MergeLayers should not mutate the layers")
+	}
+	if len(values["Tags"].([]interface{})) != 1 {
+		t.Error("MergeLayers should not mutate the layers' lists")
+	}
+}
+
+func TestMergeLayers_MergeByName(t *testing.T) {
+	projects := []map[string]interface{}{
+		{"name": "Alpha", "port": "1"},
+		{"name": "Beta", "port": "2"},
+	}
+	overrides := []interface{}{
+		map[string]interface{}{"name": "Beta", "port": "3", "enabled": true},
+		map[string]interface{}{"name": "Gamma"},
+		"unnamed",
+	}
+	layers := []ValueLayer{
+		{Name: "projects.json", Values: map[string]interface{}{"Projects": projects}},
+		{Name: "secret", Values: map[string]interface{}{"Projects": overrides}},
+	}
+	merged, err := MergeLayers(layers, MergeOptions{Mode: MergeDeep, Lists: ListMergeByName})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"name": "Alpha", "port": "1"},
+		map[string]interface{}{"name": "Beta", "port": "3", "enabled": true},
+		map[string]interface{}{"name": "Gamma"},
+		"unnamed",
+	}
+	if !reflect.DeepEqual(merged["Projects"], expected) {
+		t.Errorf("Projects = %v, want %v", merged["Projects"], expected)
+	}
+	if projects[1]["port"] != "2" {
+		t.Error("MergeLayers should not mutate list entries")
+	}
+}
+
+func TestMergeLayers_Conflicts(t *testing.T) {
+	layers := []ValueLayer{
+		{Name: "values.json", Values: map[string]interface{}{
+			"Redis": map[string]interface{}{"Host": "localhost"},
+			"Tags":  []interface{}{"a"},
+		}},
+		{Name: "ports.json", Values: map[string]interface{}{"Redis": map[string]interface{}{"Port": map[string]interface{}{"Main": "6379"}}}},
+		{Name: "secret", Values: map[string]interface{}{
+			"Redis": map[string]interface{}{"Port": "6380"},
+			"Tags":  "b",
+		}},
+	}
+	_, err := MergeLayers(layers, MergeOptions{Mode: MergeDeep})
+	var conflictErr *MergeConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected a *MergeConflictError, got %v", err)
+	}
+	expected := []MergeConflict{
+		{Path: "Redis.Port", EarlierLayer: "ports.json", LaterLayer: "secret", EarlierType: "map", LaterType: "string"},
+		{Path: "Tags", EarlierLayer: "values.json", LaterLayer: "secret", EarlierType: "list", LaterType: "string"},
+	}
+	if !reflect.DeepEqual(conflictErr.Conflicts, expected) {
+		t.Errorf("Conflicts = %+v, want %+v", conflictErr.Conflicts, expected)
+	}
+	if !strings.Contains(err.Error(), "Redis.Port: map in ports.json, string in secret") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestMergeOptions_Validate(t *testing.T) {
+	if err := (MergeOptions{Mode: MergeDeep, Lists: ListAppend}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (MergeOptions{Mode: "recursive"}).Validate(); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+	if err := (MergeOptions{Lists: "union"}).Validate(); err == nil {
+		t.Error("expected an error for an unknown list merge")
+	}
+}
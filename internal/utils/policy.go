@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SensitiveOutputRule marks the build outputs matching Pattern as sensitive
+type SensitiveOutputRule struct {
+	// Pattern is a glob matched against the build-relative output path. A pattern
+	// without a slash matches the file name alone, and ** matches any number of directories.
+	Pattern string `json:"pattern"`
+	// Mode is the octal permission mode for matching outputs, e.g. "0400" (default "0400")
+	Mode string `json:"mode,omitempty"`
+	// Owner and Group optionally set the ownership of matching outputs, by name or numeric id
+	Owner string `json:"owner,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+// OutputPolicy describes which generated files hold secrets and how to protect them
+type OutputPolicy struct {
+	SensitiveOutputs []SensitiveOutputRule `json:"sensitiveOutputs"`
+	// SecretKeys lists value keys whose values must never appear in a file that no rule matches
+	SecretKeys []string `json:"secretKeys"`
+}
+
+// DefaultOutputPolicy returns the policy used when no policy file exists:
+// .env and .secret files are read-only for their owner
+func DefaultOutputPolicy() *OutputPolicy {
+	return &OutputPolicy{
+		SensitiveOutputs: []SensitiveOutputRule{
+			{Pattern: "*.env", Mode: "0400"},
+			{Pattern: "*.secret", Mode: "0400"},
+		},
+	}
+}
+
+// LoadOutputPolicy reads and parses the output policy file, falling back to
+// DefaultOutputPolicy when the file does not exist
+func LoadOutputPolicy(filename string) (*OutputPolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultOutputPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to read file '%s': %w. Please check file permissions", filename, err)
+	}
+
+	var policy OutputPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, FormatJSONError(filename, err)
+	}
+
+	for i, rule := range policy.SensitiveOutputs {
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("invalid output policy '%s': sensitiveOutputs[%d] has no pattern", filename, i)
+		}
+	}
+
+	return &policy, nil
+}
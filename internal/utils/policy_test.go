@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadOutputPolicy_Valid(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "output-policy.json")
+	data := `{"sensitiveOutputs": [{"pattern": "*.yaml", "mode": "0440", "group": "vibeops"}], "secretKeys": ["RedisPassword"]}`
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadOutputPolicy(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &OutputPolicy{
+		SensitiveOutputs: []SensitiveOutputRule{{Pattern: "*.yaml", Mode: "0440", Group: "vibeops"}},
+		SecretKeys:       []string{"RedisPassword"},
+	}
+	if !reflect.DeepEqual(policy, expected) {
+		t.Errorf("expected %+v, got %+v", expected, policy)
+	}
+}
+
+func TestLoadOutputPolicy_NotExist(t *testing.T) {
+	policy, err := LoadOutputPolicy("/nonexistent/path/output-policy.json")
+	if err != nil {
+		t.Fatalf("expected default policy for missing file, got error: %v", err)
+	}
+	if !reflect.DeepEqual(policy, DefaultOutputPolicy()) {
+		t.Errorf("expected default policy, got %+v", policy)
+	}
+}
+
+func TestLoadOutputPolicy_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"invalid JSON":    `{bad json}`,
+		"missing pattern": `{"sensitiveOutputs": [{"mode": "0400"}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, "output-policy.json")
+			if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadOutputPolicy(file); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
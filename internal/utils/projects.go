@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 )
 
 // VibeIndex represents optional metadata for a project used in templating and automation
@@ -42,9 +44,23 @@ func LoadProjects(filename string) ([]Project, error) {
 		return nil, fmt.Errorf("failed to read file '%s': %w. Please check file permissions", filename, err)
 	}
 
+	data, err = valuesDataAsJSON(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
 	var projects []Project
-	if err := json.Unmarshal(data, &projects); err != nil {
-		return nil, FormatJSONError(filename, err)
+	if strings.EqualFold(filepath.Ext(filename), ".toml") {
+		// A TOML document is always a table, so projects are listed as [[projects]]
+		var doc struct {
+			Projects []Project `json:"projects"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, formatValuesError(filename, err)
+		}
+		projects = doc.Projects
+	} else if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, formatValuesError(filename, err)
 	}
 
 	return projects, nil
@@ -159,3 +159,30 @@ func TestLoadProjectsMap_Valid(t *testing.T) {
 		t.Errorf("expected name='MapProject', got %v", projects[0]["name"])
 	}
 }
+
+func TestLoadProjects_Formats(t *testing.T) {
+	files := map[string]string{
+		"projects.yaml": "# Services\n- name: ProjectA\n  allowVibeDeploy: true\n  buildCommands: [make]\n  vibeIndex:\n    portKey: project-a-port\n",
+		"projects.toml": "[[projects]]\nname = \"ProjectA\"\nallowVibeDeploy = true\nbuildCommands = [\"make\"]\n\n[projects.vibeIndex]\nportKey = \"project-a-port\"\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			projects, err := LoadProjects(file)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(projects) != 1 {
+				t.Fatalf("expected 1 project, got %d", len(projects))
+			}
+			p := projects[0]
+			if p.Name != "ProjectA" || !p.AllowVibeDeploy || len(p.BuildCommands) != 1 || p.VibeIndex == nil || p.VibeIndex.PortKey != "project-a-port" {
+				t.Errorf("unexpected project: %+v", p)
+			}
+		})
+	}
+}
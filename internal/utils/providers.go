@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dirSecretProvider loads one secret value per file in a directory, the file name
+// being the key, as Docker and Kubernetes mount secrets
+type dirSecretProvider struct {
+	path string
+}
+
+func newDirSecretProvider(config SecretProviderConfig) (SecretProvider, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("dir secret provider requires Path")
+	}
+	return &dirSecretProvider{path: config.Path}, nil
+}
+
+func (p *dirSecretProvider) Name() string {
+	return "secret directory " + p.path
+}
+
+// Load reads every regular file in the directory, following symlinks, with a
+// single trailing newline trimmed. Subdirectories and hidden files, such as the
+// ..data link of a Kubernetes secret volume, are skipped.
+func (p *dirSecretProvider) Load(ctx context.Context) (*Secret, error) {
+	entries, err := os.ReadDir(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret directory: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(p.path, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %s: %w", entry.Name(), err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %s: %w", entry.Name(), err)
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		values[entry.Name()] = strings.TrimSuffix(value, "\r")
+	}
+	return &Secret{Values: values, Version: p.path}, nil
+}
+
+// execSecretProvider runs a helper command that prints the secret values as a
+// JSON object on stdout
+type execSecretProvider struct {
+	command []string
+}
+
+func newExecSecretProvider(config SecretProviderConfig) (SecretProvider, error) {
+	if len(config.Command) == 0 || config.Command[0] == "" {
+		return nil, fmt.Errorf("exec secret provider requires Command")
+	}
+	return &execSecretProvider{command: config.Command}, nil
+}
+
+func (p *execSecretProvider) Name() string {
+	return "secret helper " + p.command[0]
+}
+
+// Load runs the command with the environment of vibeops. Its stderr is included
+// in the error when it fails, and is otherwise discarded.
+func (p *execSecretProvider) Load(ctx context.Context) (*Secret, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("secret helper %s failed: %w: %s", p.command[0], err, message)
+		}
+		return nil, fmt.Errorf("secret helper %s failed: %w", p.command[0], err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse the output of secret helper %s as a JSON object: %w", p.command[0], err)
+	}
+	return &Secret{Values: values, Version: p.command[0]}, nil
+}
@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// schemaTypes are the JSON Schema types a property may declare
+var schemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"object": true, "array": true, "null": true,
+}
+
+// ValuesSchema is the subset of JSON Schema that describes the merged template
+// values: an object whose properties declare the type, pattern, allowed values,
+// default and secrecy of each key
+type ValuesSchema struct {
+	SchemaProperty
+}
+
+// SchemaProperty describes one value. Secret is an extension to JSON Schema that
+// marks values which must never appear in a file the output policy does not protect.
+type SchemaProperty struct {
+	Type        SchemaType                 `json:"type,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Pattern     string                     `json:"pattern,omitempty"`
+	Enum        []interface{}              `json:"enum,omitempty"`
+	Default     interface{}                `json:"default,omitempty"`
+	Secret      bool                       `json:"secret,omitempty"`
+	Required    []string                   `json:"required,omitempty"`
+	Properties  map[string]*SchemaProperty `json:"properties,omitempty"`
+	Items       *SchemaProperty            `json:"items,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// SchemaType is the type or types a property allows; JSON Schema accepts either
+// a single type name or a list of them
+type SchemaType []string
+
+// UnmarshalJSON accepts "string" as well as ["string", "null"]
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a type name or a list of type names")
+	}
+	*t = list
+	return nil
+}
+
+// SchemaViolation is a value that does not match the schema
+type SchemaViolation struct {
+	// Path is the dotted path of the value, e.g. "Redis.Port" or "Projects[0].name"
+	Path    string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// LoadValuesSchema reads and parses a values schema, returning nil when the file
+// does not exist. Patterns are compiled and types checked up front, so a broken
+// schema is reported before any values are checked against it.
+func LoadValuesSchema(filename string) (*ValuesSchema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read file '%s': %w. Please check file permissions", filename, err)
+	}
+
+	var schema ValuesSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, FormatJSONError(filename, err)
+	}
+	if err := schema.compile(""); err != nil {
+		return nil, fmt.Errorf("invalid values schema '%s': %w", filename, err)
+	}
+	return &schema, nil
+}
+
+// compile checks the types and compiles the patterns of p and every nested property
+func (p *SchemaProperty) compile(path string) error {
+	for _, name := range p.Type {
+		if !schemaTypes[name] {
+			return fmt.Errorf("%s: unknown type %q", schemaPath(path), name)
+		}
+	}
+	if p.Pattern != "" {
+		pattern, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", schemaPath(path), err)
+		}
+		p.pattern = pattern
+	}
+	for _, key := range sortedPropertyKeys(p.Properties) {
+		if err := p.Properties[key].compile(joinSchemaPath(path, key)); err != nil {
+			return err
+		}
+	}
+	if p.Items != nil {
+		return p.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// SecretKeys returns the top-level keys the schema marks as secret, sorted
+func (s *ValuesSchema) SecretKeys() []string {
+	var keys []string
+	for _, key := range sortedPropertyKeys(s.Properties) {
+		if s.Properties[key].Secret {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Defaults returns the top-level keys that have a default, with their defaults
+func (s *ValuesSchema) Defaults() map[string]interface{} {
+	defaults := make(map[string]interface{})
+	for key, property := range s.Properties {
+		if property.Default != nil {
+			defaults[key] = property.Default
+		}
+	}
+	return defaults
+}
+
+// ApplyDefaults returns a copy of values with the default of every missing key
+// filled in, including keys nested in objects that are present. values is not modified.
+func (s *ValuesSchema) ApplyDefaults(values map[string]interface{}) map[string]interface{} {
+	return s.applyDefaults(copyValue(values).(map[string]interface{}))
+}
+
+// applyDefaults fills in the defaults of p's properties in values, which it owns
+func (p *SchemaProperty) applyDefaults(values map[string]interface{}) map[string]interface{} {
+	for key, property := range p.Properties {
+		value, ok := values[key]
+		if !ok && property.Default != nil {
+			value = copyValue(property.Default)
+			values[key] = value
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			property.applyDefaults(nested)
+		}
+	}
+	return values
+}
+
+// Validate checks values against the schema and returns every violation,
+// sorted by path. Keys the schema does not mention are allowed.
+func (s *ValuesSchema) Validate(values map[string]interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	s.validate("", values, &violations)
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Path < violations[j].Path
+	})
+	return violations
+}
+
+// validate checks value at path against p, appending any violations
+func (p *SchemaProperty) validate(path string, value interface{}, violations *[]SchemaViolation) {
+	report := func(format string, args ...interface{}) {
+		*violations = append(*violations, SchemaViolation{Path: schemaPath(path), Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(p.Type) > 0 && !p.Type.allows(value) {
+		report("expected %s, got %s", strings.Join(p.Type, " or "), ValueType(value))
+		return
+	}
+	if len(p.Enum) > 0 && !enumContains(p.Enum, value) {
+		report("must be one of %s", formatEnum(p.Enum))
+	}
+	if str, ok := value.(string); ok && p.pattern != nil && !p.pattern.MatchString(str) {
+		report("does not match pattern %s", p.Pattern)
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, key := range p.Required {
+			if _, ok := typed[key]; !ok {
+				*violations = append(*violations, SchemaViolation{Path: joinSchemaPath(path, key), Message: "required key is missing"})
+			}
+		}
+		for _, key := range sortedPropertyKeys(p.Properties) {
+			if nested, ok := typed[key]; ok {
+				p.Properties[key].validate(joinSchemaPath(path, key), nested, violations)
+			}
+		}
+	case []interface{}:
+		if p.Items != nil {
+			for i, item := range typed {
+				p.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+	case []map[string]interface{}:
+		if p.Items != nil {
+			for i, item := range typed {
+				p.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+	}
+}
+
+// allows reports whether value is of one of the types
+func (t SchemaType) allows(value interface{}) bool {
+	for _, name := range t {
+		switch name {
+		case "integer":
+			if number, ok := value.(float64); ok && number == math.Trunc(number) {
+				return true
+			}
+		case "string", "number", "null":
+			if ValueType(value) == name {
+				return true
+			}
+		case "boolean":
+			if ValueType(value) == "bool" {
+				return true
+			}
+		case "object":
+			if ValueType(value) == "map" {
+				return true
+			}
+		case "array":
+			if ValueType(value) == "list" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enumContains reports whether value equals one of the allowed values
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEnum lists the allowed values as JSON
+func formatEnum(enum []interface{}) string {
+	formatted := make([]string, len(enum))
+	for i, allowed := range enum {
+		data, _ := json.Marshal(allowed)
+		formatted[i] = string(data)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// joinSchemaPath appends key to a dotted path
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// schemaPath names the root of the values when path is empty
+func schemaPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+// sortedPropertyKeys returns the keys of properties, sorted
+func sortedPropertyKeys(properties map[string]*SchemaProperty) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testValuesSchema = `{
+  "required": ["OrgName", "SlackChannelID"],
+  "properties": {
+    "OrgName": {"type": "string"},
+    "SlackChannelID": {"type": "string", "pattern": "^C[0-9A-Z]+$"},
+    "RedisHost": {"type": "string", "default": "localhost"},
+    "RedisPassword": {"type": "string", "secret": true},
+    "LogLevel": {"enum": ["debug", "info"], "default": "info"},
+    "Port": {"type": ["integer", "null"]},
+    "Redis": {
+      "type": "object",
+      "required": ["Host"],
+      "properties": {"Port": {"type": "integer", "default": 6379}}
+    },
+    "Projects": {"type": "array", "items": {"type": "object", "required": ["name"]}}
+  }
+}`
+
+func writeTestSchema(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "values.schema.json")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadValuesSchema_NotExist(t *testing.T) {
+	schema, err := LoadValuesSchema(filepath.Join(t.TempDir(), "values.schema.json"))
+	if err != nil || schema != nil {
+		t.Errorf("LoadValuesSchema() = %v, %v, want no schema", schema, err)
+	}
+}
+
+func TestLoadValuesSchema_Invalid(t *testing.T) {
+	tests := []struct {
+		name, content, message string
+	}{
+		{"bad JSON", `{"properties": `, "values.schema.json"},
+		{"unknown type", `{"properties": {"Redis": {"properties": {"Port": {"type": "int"}}}}}`, `Redis.Port: unknown type "int"`},
+		{"bad pattern", `{"properties": {"Channel": {"pattern": "C[0-9"}}}`, "Channel: invalid pattern"},
+		{"bad type list", `{"properties": {"Channel": {"type": 1}}}`, "type must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadValuesSchema(writeTestSchema(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected an error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestValuesSchema_ApplyDefaults(t *testing.T) {
+	schema, err := LoadValuesSchema(writeTestSchema(t, testValuesSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := map[string]interface{}{
+		"LogLevel": "debug",
+		"Redis":    map[string]interface{}{"Host": "redis"},
+	}
+	expected := map[string]interface{}{
+		"LogLevel":  "debug",
+		"RedisHost": "localhost",
+		"Redis":     map[string]interface{}{"Host": "redis", "Port": float64(6379)},
+	}
+	if got := schema.ApplyDefaults(values); !reflect.DeepEqual(got, expected) {
+		t.Errorf("ApplyDefaults() = %v, want %v", got, expected)
+	}
+	if _, ok := values["Redis"].(map[string]interface{})["Port"]; ok {
+		t.Error("ApplyDefaults should not modify the values")
+	}
+
+	if got := schema.SecretKeys(); !reflect.DeepEqual(got, []string{"RedisPassword"}) {
+		t.Errorf("SecretKeys() = %v", got)
+	}
+	if got := schema.Defaults(); !reflect.DeepEqual(got, map[string]interface{}{"RedisHost": "localhost", "LogLevel": "info"}) {
+		t.Errorf("Defaults() = %v", got)
+	}
+}
+
+func TestValuesSchema_Validate(t *testing.T) {
+	schema, err := LoadValuesSchema(writeTestSchema(t, testValuesSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	valid := map[string]interface{}{
+		"OrgName":        "org",
+		"SlackChannelID": "C0123ABC",
+		"Port":           nil,
+		"Redis":          map[string]interface{}{"Host": "redis", "Port": float64(6379)},
+		"Projects":       []map[string]interface{}{{"name": "Poppit"}},
+		"Unknown":        "allowed",
+	}
+	if violations := schema.Validate(valid); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+
+	invalid := map[string]interface{}{
+		"SlackChannelID": "general",
+		"LogLevel":       "trace",
+		"Port":           float64(80.5),
+		"Redis":          map[string]interface{}{"Port": "6379"},
+		"Projects":       []interface{}{map[string]interface{}{"name": "Poppit"}, map[string]interface{}{}},
+	}
+	expected := []SchemaViolation{
+		{Path: "LogLevel", Message: `must be one of "debug", "info"`},
+		{Path: "OrgName", Message: "required key is missing"},
+		{Path: "Port", Message: "expected integer or null, got number"},
+		{Path: "Projects[1].name", Message: "required key is missing"},
+		{Path: "Redis.Host", Message: "required key is missing"},
+		{Path: "Redis.Port", Message: "expected integer, got string"},
+		{Path: "SlackChannelID", Message: "does not match pattern ^C[0-9A-Z]+$"},
+	}
+	if violations := schema.Validate(invalid); !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Validate() = %v, want %v", violations, expected)
+	}
+}
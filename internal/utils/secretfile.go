@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SecretKeyEnv is the environment variable an encrypted secret file's key is read
+// from when bootstrap.json names neither KeyFile nor KeyEnv
+const SecretKeyEnv = "VIBEOPS_SECRET_KEY"
+
+// encryptedSecretHeader is the first line of an encrypted secret file
+const encryptedSecretHeader = "vibeops-encrypted:v1"
+
+// GenerateSecretKey returns a new random key for encrypting secret files, base64 encoded
+func GenerateSecretKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptSecret encrypts plaintext with AES-256-GCM under a base64 encoded key,
+// returning the contents of an encrypted secret file
+func EncryptSecret(plaintext []byte, key string) ([]byte, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return []byte(encryptedSecretHeader + "\n" + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// DecryptSecret decrypts the contents of an encrypted secret file
+func DecryptSecret(data []byte, key string) ([]byte, error) {
+	header, body, _ := strings.Cut(string(data), "\n")
+	if strings.TrimSpace(header) != encryptedSecretHeader {
+		return nil, fmt.Errorf("not an encrypted secret file: expected the header %q", encryptedSecretHeader)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted secret: %w", err)
+	}
+
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted secret: too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret (wrong key or modified file?): %w", err)
+	}
+	return plaintext, nil
+}
+
+// newSecretCipher creates the AES-256-GCM cipher for a base64 encoded key
+func newSecretCipher(key string) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("invalid secret key: expected 32 bytes, got %d", len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadSecretKey reads the key of an encrypted secret file from keyFile, else from
+// the environment variable keyEnv, which defaults to $VIBEOPS_SECRET_KEY
+func ReadSecretKey(keyFile, keyEnv string) (string, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret key: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if keyEnv == "" {
+		keyEnv = SecretKeyEnv
+	}
+	key := os.Getenv(keyEnv)
+	if key == "" {
+		return "", fmt.Errorf("no secret key: set $%s", keyEnv)
+	}
+	return key, nil
+}
+
+// encryptedFileSecretProvider loads a JSON secret from a file encrypted with EncryptSecret
+type encryptedFileSecretProvider struct {
+	path, keyFile, keyEnv string
+}
+
+func newEncryptedFileSecretProvider(config SecretProviderConfig) (SecretProvider, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("file secret provider requires Path")
+	}
+	if config.KeyFile != "" && config.KeyEnv != "" {
+		return nil, fmt.Errorf("file secret provider takes KeyFile or KeyEnv, not both")
+	}
+	return &encryptedFileSecretProvider{path: config.Path, keyFile: config.KeyFile, keyEnv: config.KeyEnv}, nil
+}
+
+func (p *encryptedFileSecretProvider) Name() string {
+	return "encrypted file " + p.path
+}
+
+// Load decrypts the file; its version is a hash of the encrypted contents, so it
+// changes whenever the file is re-encrypted
+func (p *encryptedFileSecretProvider) Load(ctx context.Context) (*Secret, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted secret: %w", err)
+	}
+	key, err := ReadSecretKey(p.keyFile, p.keyEnv)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := DecryptSecret(data, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse secret as JSON: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return &Secret{Values: values, Version: p.path + "@sha256:" + hex.EncodeToString(sum[:6])}, nil
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
@@ -11,7 +14,13 @@ import (
 
 // BootstrapConfig represents the bootstrap configuration
 type BootstrapConfig struct {
+	// GCPSecretName is a GCP secret version to load, kept as a shorthand for a
+	// "gcp" entry in Secrets
 	GCPSecretName string `json:"GCPSecretName"`
+	// Secrets are the secret providers to load values from, in merge order
+	Secrets []SecretProviderConfig `json:"Secrets"`
+	// ValuesMerge configures how the values sources are merged (shallow by default)
+	ValuesMerge MergeOptions `json:"ValuesMerge"`
 }
 
 // LoadBootstrapConfig reads and parses the bootstrap.json file
@@ -35,11 +44,140 @@ func LoadBootstrapConfig(filename string) (*BootstrapConfig, error) {
 	return &config, nil
 }
 
+// Providers creates the secret providers the config selects, in merge order:
+// GCPSecretName first, then each entry of Secrets
+func (c *BootstrapConfig) Providers() ([]SecretProvider, error) {
+	var providers []SecretProvider
+	if c.GCPSecretName != "" {
+		provider, err := NewSecretProvider(SecretProviderConfig{Type: "gcp", SecretName: c.GCPSecretName})
+		if err != nil {
+			return nil, fmt.Errorf("GCPSecretName: %w", err)
+		}
+		providers = append(providers, provider)
+	}
+	for i, config := range c.Secrets {
+		provider, err := NewSecretProvider(config)
+		if err != nil {
+			return nil, fmt.Errorf("Secrets[%d]: %w", i, err)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// SecretProviderConfig selects and configures a secret provider in bootstrap.json.
+// Each provider type uses only some of the fields.
+type SecretProviderConfig struct {
+	// Type is the registered provider type, e.g. "gcp", "file", "dir" or "exec"
+	Type string `json:"Type"`
+	// SecretName is the GCP secret version to access (gcp)
+	SecretName string `json:"SecretName,omitempty"`
+	// Path is the encrypted file (file) or the directory of secret files (dir)
+	Path string `json:"Path,omitempty"`
+	// KeyFile and KeyEnv name the file or environment variable holding the key of
+	// an encrypted file; the key is read from $VIBEOPS_SECRET_KEY by default (file)
+	KeyFile string `json:"KeyFile,omitempty"`
+	KeyEnv  string `json:"KeyEnv,omitempty"`
+	// Command is the helper command and its arguments (exec)
+	Command []string `json:"Command,omitempty"`
+}
+
+// Secret holds the values loaded from a secret provider and the version they
+// were read from, e.g. the fully-qualified GCP secret version
+type Secret struct {
+	Values  map[string]interface{}
+	Version string
+}
+
+// SecretProvider loads secret values from one secret store
+type SecretProvider interface {
+	// Name identifies the secret in messages and value provenance,
+	// e.g. "GCP secret projects/p/secrets/s/versions/latest"
+	Name() string
+	// Load reads the secret values
+	Load(ctx context.Context) (*Secret, error)
+}
+
+// SecretProviderFactory creates a provider from its bootstrap.json configuration,
+// checking the configuration without loading anything
+type SecretProviderFactory func(config SecretProviderConfig) (SecretProvider, error)
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProviderFactory{
+		"gcp":  newGCPSecretProvider,
+		"file": newEncryptedFileSecretProvider,
+		"dir":  newDirSecretProvider,
+		"exec": newExecSecretProvider,
+	}
+)
+
+// RegisterSecretProvider makes a provider type available to bootstrap.json,
+// replacing any provider registered under the same type
+func RegisterSecretProvider(typ string, factory SecretProviderFactory) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[typ] = factory
+}
+
+// SecretProviderTypes returns the registered provider types, sorted
+func SecretProviderTypes() []string {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	types := make([]string, 0, len(secretProviders))
+	for typ := range secretProviders {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewSecretProvider creates the provider of the registered type config selects
+func NewSecretProvider(config SecretProviderConfig) (SecretProvider, error) {
+	secretProvidersMu.RLock()
+	factory, ok := secretProviders[config.Type]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown secret provider type %q: expected one of %s", config.Type, strings.Join(SecretProviderTypes(), ", "))
+	}
+	return factory(config)
+}
+
+// gcpSecretProvider loads a JSON secret from GCP Secret Manager
+type gcpSecretProvider struct {
+	secretName string
+}
+
+func newGCPSecretProvider(config SecretProviderConfig) (SecretProvider, error) {
+	if config.SecretName == "" {
+		return nil, fmt.Errorf("gcp secret provider requires SecretName")
+	}
+	return &gcpSecretProvider{secretName: config.SecretName}, nil
+}
+
+func (p *gcpSecretProvider) Name() string {
+	return "GCP secret " + p.secretName
+}
+
+func (p *gcpSecretProvider) Load(ctx context.Context) (*Secret, error) {
+	return AccessGCPSecret(ctx, p.secretName)
+}
+
 // LoadGCPSecret loads a secret from GCP Secret Manager and returns it as a map
 func LoadGCPSecret(ctx context.Context, secretName string) (map[string]interface{}, error) {
+	secret, err := AccessGCPSecret(ctx, secretName)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Values, nil
+}
+
+// AccessGCPSecret loads a secret from GCP Secret Manager, recording the resolved
+// version (e.g. ".../versions/7" when "latest" was requested)
+func AccessGCPSecret(ctx context.Context, secretName string) (*Secret, error) {
 	if secretName == "" {
-		// Return empty map if no secret is configured
-		return make(map[string]interface{}), nil
+		// Return empty values if no secret is configured
+		return &Secret{Values: make(map[string]interface{})}, nil
 	}
 
 	// Create the Secret Manager client
@@ -66,5 +204,10 @@ func LoadGCPSecret(ctx context.Context, secretName string) (map[string]interface
 		return nil, fmt.Errorf("failed to parse secret as JSON: %w", err)
 	}
 
-	return secretValues, nil
+	version := result.Name
+	if version == "" {
+		version = secretName
+	}
+
+	return &Secret{Values: secretValues, Version: version}, nil
 }
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeSecretProvider returns fixed values without any secret store
+type fakeSecretProvider struct {
+	name   string
+	values map[string]interface{}
+}
+
+func (p *fakeSecretProvider) Name() string { return "fake " + p.name }
+
+func (p *fakeSecretProvider) Load(ctx context.Context) (*Secret, error) {
+	return &Secret{Values: p.values, Version: p.name + "@1"}, nil
+}
+
+func TestBootstrapConfigProviders(t *testing.T) {
+	RegisterSecretProvider("fake", func(config SecretProviderConfig) (SecretProvider, error) {
+		return &fakeSecretProvider{name: config.Path}, nil
+	})
+
+	config := &BootstrapConfig{
+		GCPSecretName: "projects/p/secrets/s/versions/latest",
+		Secrets: []SecretProviderConfig{
+			{Type: "fake", Path: "a"},
+			{Type: "dir", Path: "/run/secrets"},
+			{Type: "exec", Command: []string{"op", "inject"}},
+			{Type: "file", Path: "secrets.json.enc"},
+		},
+	}
+	providers, err := config.Providers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	expected := []string{
+		"GCP secret projects/p/secrets/s/versions/latest",
+		"fake a",
+		"secret directory /run/secrets",
+		"secret helper op",
+		"encrypted file secrets.json.enc",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("providers = %v, want %v", names, expected)
+	}
+
+	tests := []struct {
+		config  SecretProviderConfig
+		message string
+	}{
+		{SecretProviderConfig{Type: "vault"}, `unknown secret provider type "vault": expected one of dir, exec, fake, file, gcp`},
+		{SecretProviderConfig{Type: "gcp"}, "requires SecretName"},
+		{SecretProviderConfig{Type: "dir"}, "requires Path"},
+		{SecretProviderConfig{Type: "exec"}, "requires Command"},
+		{SecretProviderConfig{Type: "file", Path: "s.enc", KeyFile: "k", KeyEnv: "K"}, "not both"},
+	}
+	for _, tt := range tests {
+		config := &BootstrapConfig{Secrets: []SecretProviderConfig{tt.config}}
+		if _, err := config.Providers(); err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("Providers() with %+v: expected an error containing %q, got %v", tt.config, tt.message, err)
+		}
+	}
+}
+
+func TestEncryptedFileSecretProvider(t *testing.T) {
+	key, err := GenerateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := EncryptSecret([]byte(`{"RedisPassword": "hunter2"}`), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encrypted), "hunter2") {
+		t.Fatal("expected the secret to be encrypted")
+	}
+	file := filepath.Join(t.TempDir(), "secrets.json.enc")
+	if err := os.WriteFile(file, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewSecretProvider(SecretProviderConfig{Type: "file", Path: file, KeyEnv: "TEST_SECRET_KEY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET_KEY", key)
+	secret, err := provider.Load(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(secret.Values, map[string]interface{}{"RedisPassword": "hunter2"}) {
+		t.Errorf("Values = %v", secret.Values)
+	}
+	if !strings.HasPrefix(secret.Version, file+"@sha256:") {
+		t.Errorf("Version = %q", secret.Version)
+	}
+
+	otherKey, _ := GenerateSecretKey()
+	t.Setenv("TEST_SECRET_KEY", otherKey)
+	if _, err := provider.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("expected a decryption error, got %v", err)
+	}
+	t.Setenv("TEST_SECRET_KEY", "")
+	if _, err := provider.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "set $TEST_SECRET_KEY") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+	if _, err := DecryptSecret([]byte(`{"RedisPassword": "hunter2"}`), key); err == nil || !strings.Contains(err.Error(), "not an encrypted secret file") {
+		t.Errorf("expected an error for a plain file, got %v", err)
+	}
+}
+
+func TestDirSecretProvider(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"RedisPassword":   "hunter2\n",
+		"SlackBotToken":   "xoxb-1",
+		".hidden":         "skipped",
+		"nested/Password": "skipped",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	provider, err := NewSecretProvider(SecretProviderConfig{Type: "dir", Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := provider.Load(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"RedisPassword": "hunter2", "SlackBotToken": "xoxb-1"}
+	if !reflect.DeepEqual(secret.Values, expected) {
+		t.Errorf("Values = %v, want %v", secret.Values, expected)
+	}
+}
+
+// TestSecretHelperProcess is run as the helper command of the exec provider tests
+func TestSecretHelperProcess(t *testing.T) {
+	output, ok := os.LookupEnv("VIBEOPS_TEST_SECRET_HELPER")
+	if !ok {
+		return
+	}
+	if output == "fail" {
+		fmt.Fprint(os.Stderr, "not signed in")
+		os.Exit(1)
+	}
+	fmt.Print(output)
+	os.Exit(0)
+}
+
+func TestExecSecretProvider(t *testing.T) {
+	provider, err := NewSecretProvider(SecretProviderConfig{Type: "exec", Command: []string{os.Args[0], "-test.run=^TestSecretHelperProcess$"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("VIBEOPS_TEST_SECRET_HELPER", `{"RedisPassword": "hunter2"}`)
+	secret, err := provider.Load(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(secret.Values, map[string]interface{}{"RedisPassword": "hunter2"}) {
+		t.Errorf("Values = %v", secret.Values)
+	}
+
+	t.Setenv("VIBEOPS_TEST_SECRET_HELPER", "fail")
+	if _, err := provider.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "not signed in") {
+		t.Errorf("expected the helper's stderr in the error, got %v", err)
+	}
+	t.Setenv("VIBEOPS_TEST_SECRET_HELPER", "[1, 2]")
+	if _, err := provider.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "as a JSON object") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
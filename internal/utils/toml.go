@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const tomlSyntaxSuggestion = "Please check the TOML syntax and ensure the file is properly formatted"
+
+// FormatTOMLError creates a user-friendly error message for TOML parsing failures
+func FormatTOMLError(filename string, err error) error {
+	errMsg := err.Error()
+
+	// Extract line information if available
+	if strings.Contains(errMsg, "line") {
+		return fmt.Errorf("invalid TOML in file '%s': %s\n%s", filename, errMsg, tomlSyntaxSuggestion)
+	}
+
+	return fmt.Errorf("failed to parse TOML in file '%s': %w\n%s", filename, err, tomlSyntaxSuggestion)
+}
+
+// ValidateTOML validates that the given data is valid TOML
+func ValidateTOML(data []byte, filename string) error {
+	var parsed map[string]interface{}
+	if err := toml.Unmarshal(data, &parsed); err != nil {
+		return FormatTOMLError(filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTOML_Valid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"simple key-value", []byte("key = \"value\"\n")},
+		{"table", []byte("[outer]\ninner = 1\n")},
+		{"array of tables", []byte("[[projects]]\nname = \"a\"\n")},
+		{"empty", []byte("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTOML(tt.data, "test.toml"); err != nil {
+				t.Errorf("ValidateTOML() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTOML_Invalid(t *testing.T) {
+	err := ValidateTOML([]byte("key = \"unterminated\n"), "myfile.toml")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "invalid TOML in file 'myfile.toml'") || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("error message should contain the filename and line, got: %v", err)
+	}
+}
@@ -4,27 +4,114 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
-// LoadValuesFromFile reads and parses the specified file
+// ValuesFileExtensions are the supported values file formats, in order of preference
+var ValuesFileExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// ValuesFileCandidates returns every file name a values file may use, e.g.
+// values.json, values.yaml, values.yml and values.toml for "values"
+func ValuesFileCandidates(name string) []string {
+	candidates := make([]string, 0, len(ValuesFileExtensions))
+	for _, ext := range ValuesFileExtensions {
+		candidates = append(candidates, name+ext)
+	}
+	return candidates
+}
+
+// FindValuesFile returns the values file for name ("values", "ports" or "projects")
+// in whichever supported format exists. When none exists it returns the JSON name,
+// so callers report a missing file as before; when several exist it is an error.
+func FindValuesFile(name string) (string, error) {
+	var found []string
+	for _, candidate := range ValuesFileCandidates(name) {
+		if _, err := os.Stat(candidate); err == nil {
+			found = append(found, candidate)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return name + ".json", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("found %s; keep only one of them", strings.Join(found, " and "))
+	}
+}
+
+// LoadValuesFromFile reads and parses the specified file. YAML (.yaml, .yml) and
+// TOML (.toml) files are accepted as well as JSON and produce the same values as
+// the equivalent JSON would.
 func LoadValuesFromFile(filename string) (map[string]interface{}, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
-			fmt.Println("File does not exist, returning empty values map:", filename)
+			fmt.Fprintln(os.Stderr, "File does not exist, returning empty values map:", filename)
 			return make(map[string]interface{}), nil
 		}
 		return nil, fmt.Errorf("failed to read file '%s': %w. Please check file permissions", filename, err)
 	}
 
+	data, err = valuesDataAsJSON(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
 	var values map[string]interface{}
 	if err := json.Unmarshal(data, &values); err != nil {
-		return nil, FormatJSONError(filename, err)
+		return nil, formatValuesError(filename, err)
+	}
+	// An empty YAML document holds no values
+	if values == nil {
+		values = make(map[string]interface{})
 	}
 
 	return values, nil
 }
 
+// valuesDataAsJSON converts the contents of a YAML or TOML values file to JSON, so
+// that every format decodes to exactly the same types (float64 numbers,
+// []interface{} lists and map[string]interface{} objects). JSON is returned as is.
+func valuesDataAsJSON(filename string, data []byte) ([]byte, error) {
+	var parsed interface{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &parsed); err != nil {
+			return nil, FormatYAMLError(filename, err)
+		}
+	case ".toml":
+		var table map[string]interface{}
+		if err := toml.Unmarshal(data, &table); err != nil {
+			return nil, FormatTOMLError(filename, err)
+		}
+		parsed = table
+	default:
+		return data, nil
+	}
+
+	converted, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, formatValuesError(filename, err)
+	}
+	return converted, nil
+}
+
+// formatValuesError formats a decoding error for a values file according to its format
+func formatValuesError(filename string, err error) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return FormatYAMLError(filename, err)
+	case ".toml":
+		return FormatTOMLError(filename, err)
+	}
+	return FormatJSONError(filename, err)
+}
+
 // MergeValues merges two maps
 // Values from the second map will override any existing values with the same key
 func MergeValues(map1, map2 map[string]interface{}) map[string]interface{} {
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -109,3 +111,94 @@ func TestLoadValuesFromFile_Types(t *testing.T) {
 		t.Errorf("expected strVal='hello', got %v", values["strVal"])
 	}
 }
+
+func TestLoadValuesFromFile_Formats(t *testing.T) {
+	files := map[string]string{
+		"values.json": `{"name": "vibe", "port": 8080, "enabled": true, "tags": ["a", "b"], "nested": {"key": "value"}, "cert": "line1\nline2\n"}`,
+		"values.yaml": "# comments are allowed\nname: vibe\nport: 8080\nenabled: true\ntags: [a, b]\nnested:\n  key: value\ncert: |\n  line1\n  line2\n",
+		"values.yml":  "name: vibe\nport: 8080\nenabled: true\ntags:\n  - a\n  - b\nnested: {key: value}\ncert: \"line1\\nline2\\n\"\n",
+		"values.toml": "# comments are allowed\nname = \"vibe\"\nport = 8080\nenabled = true\ntags = [\"a\", \"b\"]\ncert = \"\"\"\nline1\nline2\n\"\"\"\n\n[nested]\nkey = \"value\"\n",
+	}
+	dir := t.TempDir()
+	loaded := make(map[string]map[string]interface{})
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		values, err := LoadValuesFromFile(file)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		loaded[name] = values
+	}
+
+	// Every format must produce exactly the types JSON does
+	for name, values := range loaded {
+		if !reflect.DeepEqual(values, loaded["values.json"]) {
+			t.Errorf("%s = %#v, want %#v", name, values, loaded["values.json"])
+		}
+	}
+}
+
+func TestLoadValuesFromFile_EmptyYAML(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ports.yaml")
+	if err := os.WriteFile(file, []byte("# no ports yet\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := LoadValuesFromFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values == nil || len(values) != 0 {
+		t.Errorf("expected an empty map, got %#v", values)
+	}
+}
+
+func TestLoadValuesFromFile_InvalidFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"values.yaml", "key:\n\tvalue\n", "invalid YAML in file"},
+		{"values.yml", "- a\n- b\n", "failed to parse YAML in file"},
+		{"values.toml", "key = \n", "invalid TOML in file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadValuesFromFile(file)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) || !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("expected an error containing %q and the file name, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestFindValuesFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if got, err := FindValuesFile("values"); err != nil || got != "values.json" {
+		t.Errorf("FindValuesFile() with no file = %q, %v, want values.json", got, err)
+	}
+
+	if err := os.WriteFile("values.yaml", []byte("a: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := FindValuesFile("values"); err != nil || got != "values.yaml" {
+		t.Errorf("FindValuesFile() = %q, %v, want values.yaml", got, err)
+	}
+
+	if err := os.WriteFile("values.json", []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindValuesFile("values"); err == nil || !strings.Contains(err.Error(), "values.json and values.yaml") {
+		t.Errorf("expected an error naming both files, got %v", err)
+	}
+}
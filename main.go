@@ -20,6 +20,11 @@ func main() {
 	rootCmd.AddCommand(cmd.NewProjectCmd())
 	rootCmd.AddCommand(cmd.NewDiffCmd())
 	rootCmd.AddCommand(cmd.NewValidateCmd())
+	rootCmd.AddCommand(cmd.NewRenderCmd())
+	rootCmd.AddCommand(cmd.NewDepsCmd())
+	rootCmd.AddCommand(cmd.NewImpactCmd())
+	rootCmd.AddCommand(cmd.NewValuesCmd())
+	rootCmd.AddCommand(cmd.NewSecretsCmd())
 
 	// Execute root command
 	if err := rootCmd.Execute(); err != nil {